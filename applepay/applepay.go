@@ -0,0 +1,260 @@
+// Package applepay provides Apple Pay on the web helpers for use with alfapay:
+// merchant validation and payment token encoding.
+package applepay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the default merchant validation timeout.
+const DefaultTimeout = 30 * time.Second
+
+// ErrValidationURLNotAllowed is returned when the validation URL does not point to Apple.
+var ErrValidationURLNotAllowed = errors.New("apple pay validation URL is not allowed")
+
+// AllowedHosts lists the merchant validation hosts published by Apple, including the
+// sandbox (cert) and China mainland gateways. It is the default of WithAllowedHosts.
+var AllowedHosts = []string{
+	"apple-pay-gateway.apple.com",
+	"apple-pay-gateway-nc-pod1.apple.com",
+	"apple-pay-gateway-nc-pod2.apple.com",
+	"apple-pay-gateway-nc-pod3.apple.com",
+	"apple-pay-gateway-nc-pod4.apple.com",
+	"apple-pay-gateway-nc-pod5.apple.com",
+	"apple-pay-gateway-pr-pod1.apple.com",
+	"apple-pay-gateway-pr-pod2.apple.com",
+	"apple-pay-gateway-pr-pod3.apple.com",
+	"apple-pay-gateway-pr-pod4.apple.com",
+	"apple-pay-gateway-pr-pod5.apple.com",
+	"cn-apple-pay-gateway.apple.com",
+	"cn-apple-pay-gateway-sh-pod.apple.com",
+	"cn-apple-pay-gateway-sh-pod1.apple.com",
+	"cn-apple-pay-gateway-sh-pod2.apple.com",
+	"cn-apple-pay-gateway-sh-pod3.apple.com",
+	"cn-apple-pay-gateway-tj-pod.apple.com",
+	"cn-apple-pay-gateway-tj-pod1.apple.com",
+	"cn-apple-pay-gateway-tj-pod2.apple.com",
+	"cn-apple-pay-gateway-tj-pod3.apple.com",
+	"apple-pay-gateway-cert.apple.com",
+	"cn-apple-pay-gateway-cert.apple.com",
+}
+
+// Config holds the merchant identity used for merchant validation.
+type Config struct {
+	MerchantIdentifier string          // Apple merchant ID, e.g. merchant.com.example
+	DisplayName        string          // Name shown on the payment sheet
+	Domain             string          // Domain the payment sheet is shown on (initiativeContext)
+	Certificate        tls.Certificate // Merchant identity certificate
+}
+
+// Validator performs Apple Pay merchant validation.
+type Validator struct {
+	config        Config
+	httpClient    *http.Client
+	validationURL string
+	allowedHosts  []string
+}
+
+// Option is a function that configures the validator.
+type Option func(*Validator)
+
+// WithHTTPClient sets a custom HTTP client.
+// The client is responsible for presenting the merchant identity certificate.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(v *Validator) {
+		v.httpClient = httpClient
+	}
+}
+
+// WithValidationURL sets a fixed validation URL, ignoring the one sent by the frontend.
+// It is intended for tests against a local stand-in.
+func WithValidationURL(validationURL string) Option {
+	return func(v *Validator) {
+		v.validationURL = validationURL
+	}
+}
+
+// WithAllowedHosts replaces the list of hosts accepted as validation URLs. Default: AllowedHosts.
+// A leading dot matches any subdomain.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(v *Validator) {
+		v.allowedHosts = hosts
+	}
+}
+
+// NewValidator creates a new merchant validator.
+func NewValidator(config Config, opts ...Option) *Validator {
+	v := &Validator{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates: []tls.Certificate{config.Certificate},
+					MinVersion:   tls.VersionTLS12,
+				},
+			},
+		},
+		allowedHosts: AllowedHosts,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// validationRequest represents the merchant validation request body.
+type validationRequest struct {
+	MerchantIdentifier string `json:"merchantIdentifier"`
+	DisplayName        string `json:"displayName"`
+	Initiative         string `json:"initiative"`
+	InitiativeContext  string `json:"initiativeContext"`
+}
+
+// Validate requests a merchant session from Apple.
+// The validationURL is the one received in the frontend's onvalidatemerchant event.
+// The returned opaque session must be passed to completeMerchantValidation unchanged.
+func (v *Validator) Validate(ctx context.Context, validationURL string) (json.RawMessage, error) {
+	if v.validationURL != "" {
+		validationURL = v.validationURL
+	} else if err := v.checkURL(validationURL); err != nil {
+		return nil, err
+	}
+
+	jsonBody, err := json.Marshal(&validationRequest{
+		MerchantIdentifier: v.config.MerchantIdentifier,
+		DisplayName:        v.config.DisplayName,
+		Initiative:         "web",
+		InitiativeContext:  v.config.Domain,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, validationURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("merchant validation failed (status %d): %s", resp.StatusCode, respBody)
+	}
+	if !json.Valid(respBody) {
+		return nil, errors.New("merchant validation returned invalid JSON")
+	}
+
+	return json.RawMessage(respBody), nil
+}
+
+func (v *Validator) checkURL(validationURL string) error {
+	u, err := url.Parse(validationURL)
+	if err != nil || u.Scheme != "https" {
+		return ErrValidationURLNotAllowed
+	}
+
+	host := u.Hostname()
+	for _, allowed := range v.allowedHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return ErrValidationURLNotAllowed
+}
+
+// Handler returns an HTTP handler for the frontend's onvalidatemerchant callback.
+// It expects a POST with a JSON body {"validationURL": "..."} and responds with the merchant session.
+func (v *Validator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body struct {
+			ValidationURL string `json:"validationURL"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		session, err := v.Validate(r.Context(), body.ValidationURL)
+		if errors.Is(err, ErrValidationURLNotAllowed) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "merchant validation failed", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(session)
+	})
+}
+
+// PaymentToken mirrors the ApplePayPaymentToken object received in onpaymentauthorized.
+type PaymentToken struct {
+	PaymentData           json.RawMessage `json:"paymentData"`
+	PaymentMethod         PaymentMethod   `json:"paymentMethod"`
+	TransactionIdentifier string          `json:"transactionIdentifier"`
+}
+
+// PaymentMethod describes the card used for the payment.
+type PaymentMethod struct {
+	DisplayName string `json:"displayName,omitempty"`
+	Network     string `json:"network,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// ParsePaymentToken parses the ApplePayPaymentToken JSON posted by the frontend.
+func ParsePaymentToken(data []byte) (*PaymentToken, error) {
+	var token PaymentToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode apple pay token: %w", err)
+	}
+	if len(token.PaymentData) == 0 || string(token.PaymentData) == "null" {
+		return nil, errors.New("apple pay token has no payment data")
+	}
+	return &token, nil
+}
+
+// Encode returns the base64-encoded payment data, as expected by ApplePayPaymentRequest.PaymentToken.
+func (t *PaymentToken) Encode() string {
+	return base64.StdEncoding.EncodeToString(t.PaymentData)
+}
+
+// EncodePaymentToken parses an ApplePayPaymentToken JSON and returns the encoded PaymentToken string.
+func EncodePaymentToken(data []byte) (string, error) {
+	token, err := ParsePaymentToken(data)
+	if err != nil {
+		return "", err
+	}
+	return token.Encode(), nil
+}
@@ -0,0 +1,69 @@
+package applepay_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/applepay"
+)
+
+func Example_merchantValidation() {
+	// Local stand-in for Apple's merchant validation endpoint
+	apple := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Printf("Apple received: %s\n", body)
+		fmt.Fprint(w, `{"merchantSessionIdentifier":"SSH-TEST","signature":"..."}`)
+	}))
+	defer apple.Close()
+
+	validator := applepay.NewValidator(applepay.Config{
+		MerchantIdentifier: "merchant.com.example",
+		DisplayName:        "Example Shop",
+		Domain:             "shop.example.com",
+	}, applepay.WithValidationURL(apple.URL), applepay.WithHTTPClient(apple.Client()))
+
+	// Serve validator.Handler() at the URL called from onvalidatemerchant
+	frontend := httptest.NewRecorder()
+	validator.Handler().ServeHTTP(frontend, httptest.NewRequest(http.MethodPost, "/applepay/validate",
+		strings.NewReader(`{"validationURL":"https://apple-pay-gateway.apple.com/paymentservices/startSession"}`)))
+
+	fmt.Printf("Session: %s\n", frontend.Body.String())
+
+	// Output:
+	// Apple received: {"merchantIdentifier":"merchant.com.example","displayName":"Example Shop","initiative":"web","initiativeContext":"shop.example.com"}
+	// Session: {"merchantSessionIdentifier":"SSH-TEST","signature":"..."}
+}
+
+func Example_paymentToken() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// event.payment.token from onpaymentauthorized, posted by the frontend
+	raw := []byte(`{
+		"paymentData": {"version": "EC_v1", "data": "...", "signature": "...", "header": {}},
+		"paymentMethod": {"displayName": "Visa 1111", "network": "Visa", "type": "debit"},
+		"transactionIdentifier": "2D8A..."
+	}`)
+
+	token, err := applepay.EncodePaymentToken(raw)
+	if err != nil {
+		log.Fatalf("Invalid Apple Pay token: %v", err)
+	}
+
+	resp, err := client.ApplePay.Payment(ctx, &alfapay.ApplePayPaymentRequest{
+		Merchant:     "your-merchant-name",
+		OrderNumber:  "ORDER-AP-002",
+		PaymentToken: token,
+	})
+	if err != nil {
+		log.Fatalf("Apple Pay payment failed: %v", err)
+	}
+
+	fmt.Printf("Apple Pay payment success: %t\n", resp.Success)
+}
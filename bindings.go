@@ -91,11 +91,11 @@ func (s *BindingService) Deactivate(ctx context.Context, req *UnbindRequest) (*B
 }
 
 // Extend extends the expiry date of a binding.
-// NewExpiry format: YYYYMM
+// NewExpiry format: YYYYMM, or set NewExpiryTime instead.
 func (s *BindingService) Extend(ctx context.Context, req *ExtendBindingRequest) (*BaseResponse, error) {
 	params := url.Values{}
 	params.Set("bindingId", req.BindingID)
-	params.Set("newExpiry", req.NewExpiry)
+	params.Set("newExpiry", timeParam(req.NewExpiry, req.NewExpiryTime, FormatExpiry))
 
 	if req.Language != "" {
 		params.Set("language", req.Language)
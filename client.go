@@ -22,10 +22,14 @@ const (
 
 // Client is the Alfa Payments API client.
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	userName   string
-	password   string
+	baseURL     string
+	httpClient  *http.Client
+	credentials CredentialsProvider
+	env         Environment
+
+	paymentPageURL string
+	merchantLogin  string
+	statusCache    *statusCache
 
 	// Services
 	Orders     *OrderService
@@ -39,6 +43,8 @@ type Client struct {
 	SamsungPay *SamsungPayService
 	MirPay     *MirPayService
 	YandexPay  *YandexPayService
+	Wallets    *WalletService
+	Payouts    *PayoutService
 }
 
 // ClientOption is a function that configures the client.
@@ -58,6 +64,31 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithPaymentPageURL sets the merchant payment page URL
+// (e.g. https://alfa.rbsuat.com/payment/merchants/<merchant>/payment_ru.html).
+// It is used to rebuild FormURL for orders recovered by RegisterIdempotent.
+func WithPaymentPageURL(pageURL string) ClientOption {
+	return func(c *Client) {
+		c.paymentPageURL = pageURL
+	}
+}
+
+// WithMerchantLogin sets the sub-merchant login sent with Register, RegisterPreAuth,
+// Decline and GetExtended calls whose request does not set MerchantLogin.
+func WithMerchantLogin(login string) ClientOption {
+	return func(c *Client) {
+		c.merchantLogin = login
+	}
+}
+
+// WithCredentialsProvider sets a provider consulted for credentials on every request,
+// replacing the userName and password passed to NewClient.
+func WithCredentialsProvider(provider CredentialsProvider) ClientOption {
+	return func(c *Client) {
+		c.credentials = provider
+	}
+}
+
 // WithTimeout sets a custom timeout for the HTTP client.
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(c *Client) {
@@ -68,9 +99,9 @@ func WithTimeout(timeout time.Duration) ClientOption {
 // NewClient creates a new Alfa Payments API client.
 func NewClient(userName, password string, opts ...ClientOption) *Client {
 	c := &Client{
-		baseURL:  DefaultBaseURL,
-		userName: userName,
-		password: password,
+		baseURL:     DefaultBaseURL,
+		env:         EnvSandbox,
+		credentials: StaticCredentials{UserName: userName, Password: password},
 		httpClient: &http.Client{
 			Timeout: DefaultTimeout,
 		},
@@ -92,68 +123,50 @@ func NewClient(userName, password string, opts ...ClientOption) *Client {
 	c.SamsungPay = &SamsungPayService{client: c}
 	c.MirPay = &MirPayService{client: c}
 	c.YandexPay = &YandexPayService{client: c}
+	c.Wallets = &WalletService{client: c}
+	c.Payouts = &PayoutService{client: c}
 
 	return c
 }
 
-// doRequest performs an HTTP request and decodes the response.
+// merchantLoginOr returns login if set, otherwise the client's sub-merchant login.
+func (c *Client) merchantLoginOr(login string) string {
+	if login != "" {
+		return login
+	}
+	return c.merchantLogin
+}
+
+// doRequest performs an HTTP request with credentials in the query and decodes the response.
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, query url.Values, body interface{}, result interface{}) error {
-	// Add authentication to query params
 	if query == nil {
 		query = url.Values{}
 	}
-	query.Set("userName", c.userName)
-	query.Set("password", c.password)
-
-	fullURL := fmt.Sprintf("%s%s", c.baseURL, endpoint)
-	if len(query) > 0 {
-		fullURL = fmt.Sprintf("%s?%s", fullURL, query.Encode())
+	if err := c.guardParams(query); err != nil {
+		return err
 	}
 
-	var bodyReader io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-	req.Header.Set("Accept", "application/json")
+	respBody, err := c.withCredentials(ctx, func(cred Credentials) ([]byte, error) {
+		// Add authentication to query params
+		query.Set("userName", cred.UserName)
+		query.Set("password", cred.Password)
 
-	resp, err := c.httpClient.Do(req)
+		fullURL := fmt.Sprintf("%s%s?%s", c.baseURL, endpoint, query.Encode())
+		return c.send(ctx, method, fullURL, jsonBody)
+	})
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode >= 400 {
-		return &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(respBody),
-		}
-	}
-
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to decode response: %w", err)
-		}
+		return err
 	}
-
-	return nil
+	return decodeResponse(respBody, result)
 }
 
 // doJSONRequest performs a JSON POST request.
@@ -169,49 +182,104 @@ func (c *Client) doFormRequest(ctx context.Context, endpoint string, params url.
 // doJSONRequestNoAuth performs a JSON POST request without query auth params.
 // Used for endpoints where auth is passed in the request body.
 func (c *Client) doJSONRequestNoAuth(ctx context.Context, endpoint string, body interface{}, result interface{}) error {
-	fullURL := fmt.Sprintf("%s%s", c.baseURL, endpoint)
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
 
-	var bodyReader io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
+	respBody, err := c.send(ctx, http.MethodPost, c.baseURL+endpoint, jsonBody)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(respBody, result)
+}
+
+// doJSONRequestBodyAuth performs a JSON POST request whose body carries the credentials.
+func (c *Client) doJSONRequestBodyAuth(ctx context.Context, endpoint string, body func(Credentials) interface{}, result interface{}) error {
+	respBody, err := c.withCredentials(ctx, func(cred Credentials) ([]byte, error) {
+		jsonBody, err := json.Marshal(body(cred))
 		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+		return c.send(ctx, http.MethodPost, c.baseURL+endpoint, jsonBody)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeResponse(respBody, result)
+}
+
+// withCredentials performs an attempt with the current credentials. If the gateway rejects them
+// and the provider still knows the credentials used before the last rotation, the attempt is
+// repeated once with those.
+func (c *Client) withCredentials(ctx context.Context, attempt func(Credentials) ([]byte, error)) ([]byte, error) {
+	cred, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	if err := c.guardCredentials(cred); err != nil {
+		return nil, err
+	}
+
+	respBody, err := attempt(cred)
+	if !isAuthFailure(respBody, err) {
+		return respBody, err
+	}
+
+	rotating, ok := c.credentials.(PreviousCredentialsProvider)
+	if !ok {
+		return respBody, err
+	}
+	prev, ok := rotating.PreviousCredentials()
+	if !ok || prev == cred || c.guardCredentials(prev) != nil {
+		return respBody, err
+	}
+	return attempt(prev)
+}
+
+// send performs an HTTP request and returns the response body.
+func (c *Client) send(ctx context.Context, method, fullURL string, jsonBody []byte) ([]byte, error) {
+	var bodyReader io.Reader
+	if jsonBody != nil {
 		bodyReader = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if jsonBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return &APIError{
+		return respBody, &APIError{
 			StatusCode: resp.StatusCode,
 			Message:    string(respBody),
 		}
 	}
+	return respBody, nil
+}
 
+func decodeResponse(respBody []byte, result interface{}) error {
 	if result != nil && len(respBody) > 0 {
 		if err := json.Unmarshal(respBody, result); err != nil {
 			return fmt.Errorf("failed to decode response: %w", err)
 		}
 	}
-
 	return nil
 }
 
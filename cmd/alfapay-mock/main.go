@@ -0,0 +1,69 @@
+// Command alfapay-mock runs a local emulator of the Alfa Payments gateway for end-to-end tests.
+//
+// Usage:
+//
+//	alfapay-mock [-addr :8080] [-username user -password pass] [-callback-secret key]
+//
+// Point the client at it with alfapay.WithBaseURL("http://localhost:8080/payment").
+// Registered orders are paid on the hosted page at their FormURL with the test cards listed there,
+// and the admin API under /admin/ inspects and manipulates orders (see package mockgateway).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	publicURL := flag.String("public-url", "", "gateway URL as seen by browsers, e.g. http://mock.local:8080/payment (default: from the request host)")
+	userName := flag.String("username", "", "accepted API user name (default: accept any credentials)")
+	password := flag.String("password", "", "accepted API password")
+	callbackSecret := flag.String("callback-secret", "", "key for callback checksums (default: unsigned callbacks)")
+	feeRate := flag.Float64("fee-rate", 0, "acquiring fee as a fraction of the deposited amount, e.g. 0.02")
+	quiet := flag.Bool("quiet", false, "do not log requests and callbacks")
+	flag.Parse()
+
+	opts := mockgateway.Options{
+		UserName:       *userName,
+		Password:       *password,
+		PublicURL:      *publicURL,
+		CallbackSecret: *callbackSecret,
+		FeeRate:        *feeRate,
+	}
+	if !*quiet {
+		opts.Logf = log.Printf
+	}
+	gateway := mockgateway.New(opts)
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           gateway,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("alfapay-mock listening on %s, gateway at %s, admin API at /admin/", *addr, mockgateway.PathPrefix)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	gateway.Wait()
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// errorCodeNoBindings is returned by the bindings endpoints when a client has no saved cards.
+const errorCodeNoBindings = "2"
+
+func (a *app) orderRegister(ctx context.Context, args []string) error {
+	fs := a.flags("order register")
+	number := fs.String("number", "", "merchant order number")
+	amount := fs.Int64("amount", 0, "amount in minor units, e.g. 150000 for 1500.00 RUB")
+	returnURL := fs.String("return-url", "", "URL the payer returns to after payment")
+	failURL := fs.String("fail-url", "", "URL the payer returns to after a failed payment")
+	description := fs.String("description", "", "order description")
+	clientID := fs.String("client-id", "", "client ID, to let the payer save the card")
+	email := fs.String("email", "", "payer email")
+	preAuth := fs.Bool("preauth", false, "register a two-stage order, deposited later with payment deposit")
+	if err := a.parse(fs, args, "number", "amount", "return-url"); err != nil {
+		return err
+	}
+
+	req := &alfapay.RegisterOrderRequest{
+		OrderNumber: *number,
+		Amount:      *amount,
+		ReturnURL:   *returnURL,
+		FailURL:     *failURL,
+		Description: *description,
+		ClientID:    *clientID,
+		Email:       *email,
+	}
+	register := a.client.Orders.Register
+	if *preAuth {
+		register = a.client.Orders.RegisterPreAuth
+	}
+	resp, err := register(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("order registration", resp.BaseResponse); err != nil {
+		return err
+	}
+	return a.out.fields(resp,
+		[2]string{"Order ID", resp.OrderID},
+		[2]string{"Payment URL", resp.FormURL},
+	)
+}
+
+// orderFlags adds the flags identifying an order.
+func orderFlags(a *app, name string) (fs *flag.FlagSet, id, number *string) {
+	fs = a.flags(name)
+	id = fs.String("id", "", "gateway order ID")
+	number = fs.String("number", "", "merchant order number, instead of -id")
+	return fs, id, number
+}
+
+// lookup fetches an order by ID or number.
+func (a *app) lookup(ctx context.Context, id, number string) (*alfapay.GetOrderStatusExtendedResponse, error) {
+	if (id == "") == (number == "") {
+		return nil, fmt.Errorf("either -id or -number is required")
+	}
+	status, err := a.client.Status.GetExtended(ctx, &alfapay.GetOrderStatusRequest{OrderID: id, OrderNumber: number})
+	if err != nil {
+		return nil, err
+	}
+	if err := gatewayError("order lookup", status.BaseResponse); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (a *app) orderDecline(ctx context.Context, args []string) error {
+	fs, id, number := orderFlags(a, "order decline")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if (*id == "") == (*number == "") {
+		return fmt.Errorf("either -id or -number is required")
+	}
+
+	resp, err := a.client.Orders.Decline(ctx, &alfapay.DeclineRequest{OrderID: *id, OrderNumber: *number})
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("decline", *resp); err != nil {
+		return err
+	}
+	return a.out.fields(resp, [2]string{"Result", "order declined"})
+}
+
+func (a *app) orderStatus(ctx context.Context, args []string) error {
+	fs, id, number := orderFlags(a, "order status")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	status, err := a.lookup(ctx, *id, *number)
+	if err != nil {
+		return err
+	}
+
+	pairs := [][2]string{
+		{"Order ID", status.OrderID()},
+		{"Order number", status.OrderNumber},
+		{"Status", formatStatus(status.OrderStatus)},
+		{"Amount", formatAmount(status.Amount) + " " + currencyName(status.Currency)},
+		{"Description", status.OrderDescription},
+		{"Created", formatTime(status.CreatedAt())},
+		{"Payment way", status.PaymentWay},
+		{"Card", maskedPAN(status)},
+	}
+	if status.ActionCode != 0 {
+		pairs = append(pairs, [2]string{"Action code", fmt.Sprintf("%d %s", status.ActionCode, status.ActionCodeDescription)})
+	}
+	if info := status.CardAuthInfo; info != nil {
+		pairs = append(pairs, [2]string{"Approval code", info.ApprovalCode})
+	}
+	if info := status.BankInfo; info != nil {
+		pairs = append(pairs, [2]string{"Issuer", info.BankName})
+	}
+	if info := status.PaymentAmountInfo; info != nil {
+		pairs = append(pairs,
+			[2]string{"Payment state", info.PaymentState},
+			[2]string{"Approved", formatAmount(info.ApprovedAmount)},
+			[2]string{"Deposited", formatAmount(info.DepositedAmount)},
+			[2]string{"Refunded", formatAmount(info.RefundedAmount)},
+		)
+	}
+	pairs = append(pairs,
+		[2]string{"Deposited at", formatTime(status.DepositedAt())},
+		[2]string{"Refunded at", formatTime(status.RefundedAt())},
+		[2]string{"Reversed at", formatTime(status.ReversedAt())},
+	)
+	for i := range status.Refunds {
+		refund := &status.Refunds[i]
+		pairs = append(pairs, [2]string{"Refund", formatAmount(refund.RefundAmount) + " " + formatTime(refund.RefundedAt())})
+	}
+	if status.Chargeback {
+		pairs = append(pairs, [2]string{"Chargeback", "yes"})
+	}
+	return a.out.fields(status, pairs...)
+}
+
+// moveMoney looks up an order, confirms the operation and runs it.
+func (a *app) moveMoney(ctx context.Context, id, number, action string, amount int64,
+	run func(orderID string) (*alfapay.BaseResponse, error)) error {
+	status, err := a.lookup(ctx, id, number)
+	if err != nil {
+		return err
+	}
+	orderID := status.OrderID()
+	if orderID == "" {
+		orderID = id
+	}
+
+	what := "the full amount"
+	if amount > 0 {
+		what = formatAmount(amount) + " " + currencyName(status.Currency)
+	}
+	if err := a.confirm("%s %s of order %s (%s, status %s, amount %s)", action, what, status.OrderNumber, orderID,
+		formatStatus(status.OrderStatus), formatAmount(status.Amount)); err != nil {
+		return err
+	}
+
+	resp, err := run(orderID)
+	if err != nil {
+		return err
+	}
+	if err := gatewayError(action, *resp); err != nil {
+		return err
+	}
+	return a.out.fields(resp, [2]string{"Result", action + " completed"}, [2]string{"Order ID", orderID})
+}
+
+func (a *app) paymentDeposit(ctx context.Context, args []string) error {
+	fs, id, number := orderFlags(a, "payment deposit")
+	amount := fs.Int64("amount", 0, "amount in minor units; 0 deposits the full pre-authorized amount")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	return a.moveMoney(ctx, *id, *number, "deposit", *amount, func(orderID string) (*alfapay.BaseResponse, error) {
+		return a.client.Payments.Deposit(ctx, &alfapay.DepositRequest{OrderID: orderID, Amount: *amount})
+	})
+}
+
+func (a *app) paymentReverse(ctx context.Context, args []string) error {
+	fs, id, number := orderFlags(a, "payment reverse")
+	amount := fs.Int64("amount", 0, "amount in minor units for a partial reversal; 0 reverses the full amount")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	return a.moveMoney(ctx, *id, *number, "reverse", *amount, func(orderID string) (*alfapay.BaseResponse, error) {
+		return a.client.Payments.Reverse(ctx, &alfapay.ReverseRequest{OrderID: orderID, Amount: *amount})
+	})
+}
+
+func (a *app) refund(ctx context.Context, args []string) error {
+	fs, id, number := orderFlags(a, "refund")
+	amount := fs.Int64("amount", 0, "amount to refund in minor units")
+	if err := a.parse(fs, args, "amount"); err != nil {
+		return err
+	}
+	if *amount <= 0 {
+		return fmt.Errorf("-amount must be positive")
+	}
+	return a.moveMoney(ctx, *id, *number, "refund", *amount, func(orderID string) (*alfapay.BaseResponse, error) {
+		return a.client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: orderID, Amount: *amount})
+	})
+}
+
+func (a *app) bindingsList(ctx context.Context, args []string) error {
+	fs := a.flags("bindings list")
+	clientID := fs.String("client-id", "", "client ID")
+	all := fs.Bool("all", false, "include deactivated bindings")
+	expired := fs.Bool("expired", false, "include expired cards")
+	if err := a.parse(fs, args, "client-id"); err != nil {
+		return err
+	}
+
+	req := &alfapay.GetBindingsRequest{ClientID: *clientID}
+	if *expired {
+		req.ShowExpired = "true"
+	}
+	list := a.client.Bindings.GetBindings
+	if *all {
+		list = a.client.Bindings.GetAllBindings
+	}
+	resp, err := list(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.ErrorCode == errorCodeNoBindings {
+		resp = &alfapay.GetBindingsResponse{Bindings: []alfapay.Binding{}}
+	} else if err := gatewayError("bindings list", resp.BaseResponse); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(resp.Bindings))
+	for i := range resp.Bindings {
+		b := &resp.Bindings[i]
+		expiry := b.ExpiryDate
+		if b.IsExpired {
+			expiry += " (expired)"
+		}
+		rows = append(rows, []string{b.BindingID, b.MaskedPan, expiry, b.PaymentSystem,
+			formatTime(b.CreatedAt()), formatTime(b.LastUsedAt())})
+	}
+	return a.out.table(resp.Bindings, []string{"BINDING ID", "CARD", "EXPIRY", "SYSTEM", "CREATED", "LAST USED"}, rows)
+}
+
+func (a *app) bindingsDeactivate(ctx context.Context, args []string) error {
+	fs := a.flags("bindings deactivate")
+	bindingID := fs.String("binding-id", "", "binding ID")
+	if err := a.parse(fs, args, "binding-id"); err != nil {
+		return err
+	}
+
+	resp, err := a.client.Bindings.Deactivate(ctx, &alfapay.UnbindRequest{BindingID: *bindingID})
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("deactivation", *resp); err != nil {
+		return err
+	}
+	return a.out.fields(resp, [2]string{"Result", "binding deactivated"})
+}
+
+func (a *app) bindingsExtend(ctx context.Context, args []string) error {
+	fs := a.flags("bindings extend")
+	bindingID := fs.String("binding-id", "", "binding ID")
+	expiry := fs.String("expiry", "", "new expiry month, YYYYMM")
+	if err := a.parse(fs, args, "binding-id", "expiry"); err != nil {
+		return err
+	}
+	if _, err := alfapay.ParseExpiry(*expiry); err != nil {
+		return err
+	}
+
+	resp, err := a.client.Bindings.Extend(ctx, &alfapay.ExtendBindingRequest{BindingID: *bindingID, NewExpiry: *expiry})
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("extension", *resp); err != nil {
+		return err
+	}
+	return a.out.fields(resp, [2]string{"Result", "binding extended to " + *expiry})
+}
+
+func (a *app) sbpQR(ctx context.Context, args []string) error {
+	fs := a.flags("sbp qr")
+	id := fs.String("id", "", "gateway order ID")
+	size := fs.Int("size", 0, "QR image width and height in pixels")
+	image := fs.String("image", "", "write the QR image to this file")
+	if err := a.parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	resp, err := a.client.SBP.GetQR(ctx, &alfapay.SBPGetQRRequest{MDOrder: *id, QRWidth: *size, QRHeight: *size})
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("QR request", resp.BaseResponse); err != nil {
+		return err
+	}
+	if *image != "" {
+		data, err := base64.StdEncoding.DecodeString(resp.QRImage)
+		if err != nil {
+			return fmt.Errorf("failed to decode QR image: %w", err)
+		}
+		if err := os.WriteFile(*image, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return a.out.fields(resp,
+		[2]string{"Payload", resp.Payload},
+		[2]string{"QR URL", resp.QRURL},
+		[2]string{"Image", *image},
+	)
+}
+
+func (a *app) sbpStatus(ctx context.Context, args []string) error {
+	fs := a.flags("sbp status")
+	id := fs.String("id", "", "gateway order ID")
+	if err := a.parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	resp, err := a.client.SBP.GetQRStatus(ctx, *id)
+	if err != nil {
+		return err
+	}
+	if err := gatewayError("status request", resp.BaseResponse); err != nil {
+		return err
+	}
+	return a.out.fields(resp,
+		[2]string{"Order ID", resp.OrderID},
+		[2]string{"Status", formatStatus(resp.OrderStatus)},
+	)
+}
+
+func (a *app) ordersList(ctx context.Context, args []string) error {
+	now := time.Now()
+	fs := a.flags("orders list")
+	from := fs.String("from", "", "period start: 2006-01-02, 2006-01-02T15:04:05 or yyyyMMddHHmmss, Moscow time (default: 24 hours ago)")
+	to := fs.String("to", "", "period end, in the same formats (default: now)")
+	states := fs.String("states", "", "comma-separated payment states, e.g. DEPOSITED,REFUNDED")
+	merchants := fs.String("merchants", "", "comma-separated sub-merchant logins")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	req := &alfapay.GetLastOrdersRequest{
+		FromTime:          now.Add(-24 * time.Hour),
+		ToTime:            now,
+		Size:              200,
+		TransactionStates: *states,
+		Merchants:         *merchants,
+	}
+	var err error
+	if *from != "" {
+		if req.FromTime, err = parseDate(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if req.ToTime, err = parseDate(*to); err != nil {
+			return err
+		}
+	}
+
+	orders := []*alfapay.GetOrderStatusExtendedResponse{}
+	err = a.client.Status.EachLastOrder(ctx, req, func(status *alfapay.GetOrderStatusExtendedResponse) error {
+		orders = append(orders, status)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(orders))
+	for _, status := range orders {
+		rows = append(rows, []string{formatTime(status.CreatedAt()), status.OrderID(), status.OrderNumber,
+			formatStatus(status.OrderStatus), formatAmount(status.Amount), currencyName(status.Currency),
+			maskedPAN(status), status.PaymentWay})
+	}
+	return a.out.table(orders, []string{"CREATED", "ORDER ID", "NUMBER", "STATUS", "AMOUNT", "CURRENCY", "CARD", "PAYMENT WAY"}, rows)
+}
+
+// parseDate parses a date, or anything alfapay.ParseDateTime accepts, in Moscow time.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, alfapay.MoscowTime); err == nil {
+		return t, nil
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) != len(alfapay.LastOrdersDateLayout) {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return alfapay.ParseDateTime(s)
+}
@@ -0,0 +1,239 @@
+// Command alfapay looks up orders, moves money and manages bindings from the command line.
+//
+// Usage:
+//
+//	alfapay [global flags] <command> [subcommand] [flags]
+//
+// Commands:
+//
+//	order register   register an order and print its payment page URL
+//	order decline    decline an unpaid order
+//	order status     show an order's extended status
+//	payment deposit  complete a pre-authorized payment
+//	payment reverse  cancel an authorized payment
+//	refund           refund a paid order
+//	bindings list    list a client's saved cards
+//	bindings deactivate / extend
+//	sbp qr           get an SBP QR code for an order
+//	sbp status       show an SBP QR payment status
+//	orders list      list orders for a period
+//
+// Credentials are read from ALFAPAY_USERNAME and ALFAPAY_PASSWORD, or from the secrets file
+// given by -config or ALFAPAY_CONFIG (JSON or KEY=VALUE, see alfapay.NewFileCredentialsProvider).
+// The environment is chosen with -env or ALFAPAY_ENV (sandbox by default), and -base-url or
+// ALFAPAY_BASE_URL overrides the gateway URL. Deposits, reversals and refunds ask for
+// confirmation unless -yes is given.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// errUsage is returned for invalid command lines; the usage has already been printed.
+var errUsage = errors.New("invalid usage")
+
+// errAborted is returned when the user declines a confirmation.
+var errAborted = errors.New("aborted")
+
+// app holds the state shared by commands.
+type app struct {
+	client *alfapay.Client
+	out    *printer
+	in     *bufio.Reader
+	stderr io.Writer
+	yes    bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "alfapay:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses global flags, builds the client and runs the command in args.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("alfapay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	env := fs.String("env", os.Getenv("ALFAPAY_ENV"), "gateway environment: sandbox or production")
+	baseURL := fs.String("base-url", os.Getenv("ALFAPAY_BASE_URL"), "gateway URL, overriding the environment's")
+	config := fs.String("config", os.Getenv("ALFAPAY_CONFIG"), "secrets file with the API user name and password")
+	merchant := fs.String("merchant", os.Getenv("ALFAPAY_MERCHANT_LOGIN"), "sub-merchant login")
+	output := fs.String("o", "table", "output format: table or json")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	out, err := newPrinter(stdout, *output)
+	if err != nil {
+		return err
+	}
+	opts, err := clientOptions(*env, *baseURL, *config, *merchant)
+	if err != nil {
+		return err
+	}
+
+	a := &app{
+		client: alfapay.NewClient("", "", opts...),
+		out:    out,
+		in:     bufio.NewReader(stdin),
+		stderr: stderr,
+		yes:    *yes,
+	}
+	return a.dispatch(ctx, fs.Args())
+}
+
+const usage = `Usage: alfapay [global flags] <command> [subcommand] [flags]
+
+Commands:
+  order register|decline|status
+  payment deposit|reverse
+  refund
+  bindings list|deactivate|extend
+  sbp qr|status
+  orders list
+
+Run "alfapay <command> [subcommand] -h" for the command's flags.
+
+Global flags:
+`
+
+// clientOptions configures the environment and credentials.
+func clientOptions(env, baseURL, config, merchant string) ([]alfapay.ClientOption, error) {
+	var opts []alfapay.ClientOption
+	switch strings.ToLower(env) {
+	case "", alfapay.EnvSandbox.Name:
+		opts = append(opts, alfapay.WithEnvironment(alfapay.EnvSandbox))
+	case alfapay.EnvProduction.Name:
+		opts = append(opts, alfapay.WithEnvironment(alfapay.EnvProduction))
+	default:
+		return nil, fmt.Errorf("unknown environment %q, want sandbox or production", env)
+	}
+	if baseURL != "" {
+		opts = append(opts, alfapay.WithBaseURL(baseURL))
+	}
+	if merchant != "" {
+		opts = append(opts, alfapay.WithMerchantLogin(merchant))
+	}
+
+	if config != "" {
+		provider, err := alfapay.NewFileCredentialsProvider(config, 0)
+		if err != nil {
+			return nil, err
+		}
+		return append(opts, alfapay.WithCredentialsProvider(provider)), nil
+	}
+	provider := alfapay.NewEnvCredentialsProvider("ALFAPAY_USERNAME", "ALFAPAY_PASSWORD")
+	if _, err := provider.Credentials(context.Background()); err != nil {
+		return nil, fmt.Errorf("no credentials: %w (or use -config)", err)
+	}
+	return append(opts, alfapay.WithCredentialsProvider(provider)), nil
+}
+
+// command is a leaf command: it parses its own flags from args.
+type command func(a *app, ctx context.Context, args []string) error
+
+// commands maps "command" or "command subcommand" to its implementation.
+var commands = map[string]command{
+	"order register":      (*app).orderRegister,
+	"order decline":       (*app).orderDecline,
+	"order status":        (*app).orderStatus,
+	"payment deposit":     (*app).paymentDeposit,
+	"payment reverse":     (*app).paymentReverse,
+	"refund":              (*app).refund,
+	"bindings list":       (*app).bindingsList,
+	"bindings deactivate": (*app).bindingsDeactivate,
+	"bindings extend":     (*app).bindingsExtend,
+	"sbp qr":              (*app).sbpQR,
+	"sbp status":          (*app).sbpStatus,
+	"orders list":         (*app).ordersList,
+}
+
+func (a *app) dispatch(ctx context.Context, args []string) error {
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd(a, ctx, args[1:])
+	}
+	if len(args) > 1 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd(a, ctx, args[2:])
+		}
+	}
+	fmt.Fprintf(a.stderr, "alfapay: unknown command %q\n\n%s", strings.Join(args[:min(len(args), 2)], " "), usage)
+	return errUsage
+}
+
+// flags returns a flag set for a command that reports errors to stderr.
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("alfapay "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// parse parses command flags and checks that the required ones are set.
+func (a *app) parse(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(a.stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range required {
+		if !set[name] {
+			fmt.Fprintf(a.stderr, "flag -%s is required\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// confirm asks the user to confirm a money-moving operation unless -yes was given.
+func (a *app) confirm(format string, args ...interface{}) error {
+	if a.yes {
+		return nil
+	}
+	env := a.client.Environment()
+	fmt.Fprintf(a.stderr, "[%s] "+format+"? [y/N] ", append([]interface{}{env.Name}, args...)...)
+	answer, err := a.in.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(a.stderr)
+		return errAborted
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errAborted
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// printer writes command results as aligned tables or as JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, want table or json", format)
+	}
+}
+
+// table prints v as JSON, or rows under headers as a table.
+func (p *printer) table(v interface{}, headers []string, rows [][]string) error {
+	if p.json {
+		return p.encode(v)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// fields prints v as JSON, or name/value pairs one per line, skipping empty values.
+func (p *printer) fields(v interface{}, pairs ...[2]string) error {
+	if p.json {
+		return p.encode(v)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for _, pair := range pairs {
+		if pair[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", pair[0], pair[1])
+		}
+	}
+	return tw.Flush()
+}
+
+func (p *printer) encode(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// statusNames are short names of order statuses for tables.
+var statusNames = map[alfapay.OrderStatus]string{
+	alfapay.OrderStatusRegistered:       "registered",
+	alfapay.OrderStatusPreAuthorized:    "pre-authorized",
+	alfapay.OrderStatusFullyAuthorized:  "paid",
+	alfapay.OrderStatusCancelled:        "reversed",
+	alfapay.OrderStatusRefunded:         "refunded",
+	alfapay.OrderStatusACSAuthorization: "3-D Secure",
+	alfapay.OrderStatusDeclined:         "declined",
+}
+
+func formatStatus(s alfapay.OrderStatus) string {
+	if name, ok := statusNames[s]; ok {
+		return fmt.Sprintf("%d (%s)", s, name)
+	}
+	return fmt.Sprint(int(s))
+}
+
+// formatAmount formats an amount in minor units, e.g. 150000 as 1500.00.
+func formatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// formatTime formats a gateway time in Moscow time; zero times are empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(alfapay.MoscowTime).Format("2006-01-02 15:04:05")
+}
+
+// currencyName returns the ISO 4217 code of common numeric currency codes.
+func currencyName(code string) string {
+	switch code {
+	case "", "643":
+		return "RUB"
+	case "840":
+		return "USD"
+	case "978":
+		return "EUR"
+	case "156":
+		return "CNY"
+	default:
+		return code
+	}
+}
+
+func maskedPAN(status *alfapay.GetOrderStatusExtendedResponse) string {
+	if status.CardAuthInfo == nil {
+		return ""
+	}
+	return status.CardAuthInfo.MaskedPan
+}
+
+// gatewayError converts an unsuccessful response into an error.
+func gatewayError(action string, resp alfapay.BaseResponse) error {
+	if resp.IsSuccess() {
+		return nil
+	}
+	return fmt.Errorf("%s failed: %s (error code %s)", action, resp.ErrorMessage, resp.ErrorCode)
+}
@@ -0,0 +1,272 @@
+package alfapay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCredentialsCheckInterval is the default interval between credential file checks.
+const DefaultCredentialsCheckInterval = 10 * time.Second
+
+// Credentials represents gateway API credentials.
+type Credentials struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+// CredentialsProvider supplies credentials; it is consulted on every request
+// and must be safe for concurrent use.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// PreviousCredentialsProvider is implemented by providers that remember the credentials
+// in use before the last rotation. The client retries a request rejected for authentication
+// once with the previous credentials, so rotating the local secret before the gateway
+// accepts it does not cause failures.
+type PreviousCredentialsProvider interface {
+	PreviousCredentials() (Credentials, bool)
+}
+
+// StaticCredentials is a CredentialsProvider that always returns the same credentials.
+type StaticCredentials Credentials
+
+// Credentials returns the static credentials.
+func (c StaticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// rotation tracks the current and previous credentials of a rotating provider.
+type rotation struct {
+	mu       sync.RWMutex
+	current  Credentials
+	previous Credentials
+}
+
+// set makes cred current, keeping the replaced credentials as previous.
+func (r *rotation) set(cred Credentials) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cred == r.current {
+		return
+	}
+	if r.current != (Credentials{}) {
+		r.previous = r.current
+	}
+	r.current = cred
+}
+
+func (r *rotation) get() Credentials {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// PreviousCredentials returns the credentials replaced by the last rotation.
+func (r *rotation) PreviousCredentials() (Credentials, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.previous, r.previous != (Credentials{})
+}
+
+// EnvCredentialsProvider reads credentials from environment variables on every request.
+type EnvCredentialsProvider struct {
+	userVar     string
+	passwordVar string
+	rotation
+}
+
+// NewEnvCredentialsProvider creates a provider reading the given environment variables,
+// e.g. NewEnvCredentialsProvider("ALFAPAY_USERNAME", "ALFAPAY_PASSWORD").
+func NewEnvCredentialsProvider(userVar, passwordVar string) *EnvCredentialsProvider {
+	return &EnvCredentialsProvider{userVar: userVar, passwordVar: passwordVar}
+}
+
+// Credentials returns the credentials currently set in the environment.
+func (p *EnvCredentialsProvider) Credentials(context.Context) (Credentials, error) {
+	cred := Credentials{UserName: os.Getenv(p.userVar), Password: os.Getenv(p.passwordVar)}
+	if cred.UserName == "" || cred.Password == "" {
+		return Credentials{}, fmt.Errorf("%s and %s must be set", p.userVar, p.passwordVar)
+	}
+	p.set(cred)
+	return cred, nil
+}
+
+// FileCredentialsProvider reads credentials from a secrets file and reloads it when it changes.
+// The file is either JSON ({"userName": "...", "password": "..."}) or KEY=VALUE lines
+// with USERNAME and PASSWORD keys (an optional ALFAPAY_ prefix is accepted).
+type FileCredentialsProvider struct {
+	path          string
+	checkInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+
+	rotation
+}
+
+// NewFileCredentialsProvider creates a provider for a secrets file, checked for changes
+// at most every checkInterval (DefaultCredentialsCheckInterval if not positive).
+// The file must be readable when the provider is created.
+func NewFileCredentialsProvider(path string, checkInterval time.Duration) (*FileCredentialsProvider, error) {
+	if checkInterval <= 0 {
+		checkInterval = DefaultCredentialsCheckInterval
+	}
+	p := &FileCredentialsProvider{path: path, checkInterval: checkInterval, now: time.Now}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Credentials returns the cached credentials, reloading the file if it has changed.
+// If a reload fails, the last good credentials are returned.
+func (p *FileCredentialsProvider) Credentials(context.Context) (Credentials, error) {
+	p.mu.Lock()
+	due := p.now().Sub(p.lastCheck) >= p.checkInterval
+	p.mu.Unlock()
+
+	if due {
+		_ = p.reload()
+	}
+	return p.get(), nil
+}
+
+// Reload rereads the file immediately, e.g. on SIGHUP.
+func (p *FileCredentialsProvider) Reload() error {
+	return p.reload()
+}
+
+func (p *FileCredentialsProvider) reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastCheck = p.now()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	cred, err := parseCredentialsFile(data)
+	if err != nil {
+		return err
+	}
+
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.set(cred)
+	return nil
+}
+
+func parseCredentialsFile(data []byte) (Credentials, error) {
+	var cred Credentials
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &cred); err != nil {
+			return Credentials{}, fmt.Errorf("failed to decode credentials file: %w", err)
+		}
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if !ok || strings.HasPrefix(key, "#") {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(key)), "ALFAPAY_") {
+			case "USERNAME", "USER_NAME":
+				cred.UserName = value
+			case "PASSWORD":
+				cred.Password = value
+			}
+		}
+	}
+
+	if cred.UserName == "" || cred.Password == "" {
+		return Credentials{}, errors.New("credentials file must set userName and password")
+	}
+	return cred, nil
+}
+
+// CachedCredentialsProvider caches the credentials of another provider for a fixed time.
+type CachedCredentialsProvider struct {
+	provider CredentialsProvider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	cred    Credentials
+	expires time.Time
+}
+
+// NewCachedCredentialsProvider wraps a provider, e.g. one reading a secrets manager,
+// so it is consulted at most once per ttl.
+func NewCachedCredentialsProvider(provider CredentialsProvider, ttl time.Duration) *CachedCredentialsProvider {
+	return &CachedCredentialsProvider{provider: provider, ttl: ttl}
+}
+
+// Credentials returns the cached credentials, refreshing them when the ttl has passed.
+func (p *CachedCredentialsProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Now().Before(p.expires) {
+		return p.cred, nil
+	}
+	cred, err := p.provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.cred = cred
+	p.expires = time.Now().Add(p.ttl)
+	return cred, nil
+}
+
+// PreviousCredentials returns the previous credentials of the wrapped provider, if it tracks them.
+func (p *CachedCredentialsProvider) PreviousCredentials() (Credentials, bool) {
+	if rotating, ok := p.provider.(PreviousCredentialsProvider); ok {
+		return rotating.PreviousCredentials()
+	}
+	return Credentials{}, false
+}
+
+// isAuthFailure reports whether the gateway rejected the request credentials:
+// HTTP 401/403, or error code 5 with an access denied message. Code 5 is also used
+// for invalid parameters, so the message must name the credentials explicitly.
+func isAuthFailure(respBody []byte, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 401 || apiErr.StatusCode == 403
+	}
+	if err != nil {
+		return false
+	}
+
+	var probe BaseResponse
+	if json.Unmarshal(respBody, &probe) != nil || probe.ErrorCode != "5" {
+		return false
+	}
+	msg := strings.ToLower(probe.ErrorMessage)
+	for _, hint := range []string{"access denied", "доступ запрещ", "login or password", "логин или парол", "authentication failed"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
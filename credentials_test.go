@@ -0,0 +1,145 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// credentialsGateway accepts requests made with one user name and records the user names tried.
+type credentialsGateway struct {
+	mu       sync.Mutex
+	accepted string
+	reject   string // Error message for other user names
+	tried    []string
+}
+
+func (g *credentialsGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	user := r.URL.Query().Get("userName")
+	g.tried = append(g.tried, user)
+	if user != g.accepted {
+		w.Write([]byte(`{"errorCode":"5","errorMessage":"` + g.reject + `"}`))
+		return
+	}
+	w.Write([]byte(`{"errorCode":"0","orderStatus":2}`))
+}
+
+func (g *credentialsGateway) triedUsers() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.tried...)
+}
+
+func writeCredentialsFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCredentialsProviderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alfapay.env")
+	writeCredentialsFile(t, path, "ALFAPAY_USERNAME=old-api\nALFAPAY_PASSWORD=old", time.Unix(1000, 0))
+
+	provider, err := alfapay.NewFileCredentialsProvider(path, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if cred, _ := provider.Credentials(ctx); cred.UserName != "old-api" || cred.Password != "old" {
+		t.Fatalf("credentials = %+v, want old-api", cred)
+	}
+
+	writeCredentialsFile(t, path, `{"userName": "new-api", "password": "new"}`, time.Unix(2000, 0))
+	if cred, _ := provider.Credentials(ctx); cred.UserName != "new-api" || cred.Password != "new" {
+		t.Errorf("credentials after the file changed = %+v, want new-api", cred)
+	}
+	if prev, ok := provider.PreviousCredentials(); !ok || prev.UserName != "old-api" {
+		t.Errorf("previous credentials = %+v, %v; want old-api", prev, ok)
+	}
+
+	// A broken file keeps the last good credentials
+	writeCredentialsFile(t, path, "PASSWORD=only", time.Unix(3000, 0))
+	if err := provider.Reload(); err == nil {
+		t.Error("reload of a file without a user name succeeded")
+	}
+	if cred, _ := provider.Credentials(ctx); cred.UserName != "new-api" {
+		t.Errorf("credentials after a failed reload = %+v, want new-api", cred)
+	}
+}
+
+func TestCredentialsRotationFallback(t *testing.T) {
+	gateway := &credentialsGateway{accepted: "old-api", reject: "Access denied"}
+	srv := httptest.NewServer(gateway)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "alfapay.env")
+	writeCredentialsFile(t, path, "USERNAME=old-api\nPASSWORD=old", time.Unix(1000, 0))
+	provider, err := alfapay.NewFileCredentialsProvider(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := alfapay.NewClient("", "", alfapay.WithBaseURL(srv.URL), alfapay.WithCredentialsProvider(provider))
+	ctx := context.Background()
+
+	// The secret is rotated locally before the gateway accepts the new one
+	writeCredentialsFile(t, path, "USERNAME=new-api\nPASSWORD=new", time.Unix(2000, 0))
+	if err := provider.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Status.GetByOrderID(ctx, "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSuccess() {
+		t.Fatalf("request failed after rotation: %s", resp.ErrorMessage)
+	}
+	if tried := gateway.triedUsers(); len(tried) != 2 || tried[0] != "new-api" || tried[1] != "old-api" {
+		t.Errorf("user names tried = %v, want new-api then old-api", tried)
+	}
+}
+
+func TestCredentialsNoRetryOnInvalidParameter(t *testing.T) {
+	for _, message := range []string{"Invalid parameter", "Invalid parameter authCode"} {
+		t.Run(message, func(t *testing.T) {
+			gateway := &credentialsGateway{accepted: "old-api", reject: message}
+			srv := httptest.NewServer(gateway)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "alfapay.env")
+			writeCredentialsFile(t, path, "USERNAME=old-api\nPASSWORD=old", time.Unix(1000, 0))
+			provider, err := alfapay.NewFileCredentialsProvider(path, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeCredentialsFile(t, path, "USERNAME=new-api\nPASSWORD=new", time.Unix(2000, 0))
+			if err := provider.Reload(); err != nil {
+				t.Fatal(err)
+			}
+
+			client := alfapay.NewClient("", "", alfapay.WithBaseURL(srv.URL), alfapay.WithCredentialsProvider(provider))
+			resp, err := client.Status.GetByOrderID(context.Background(), "order-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsSuccess() {
+				t.Fatal("request with rejected parameters succeeded")
+			}
+			if tried := gateway.triedUsers(); len(tried) != 1 {
+				t.Errorf("user names tried = %v, want a single attempt", tried)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package alfapay
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrTestDataInProduction is returned when test cards or sandbox credentials
+// are used with a production environment. Nothing is sent to the gateway.
+var ErrTestDataInProduction = errors.New("test data used in production environment")
+
+// Environment describes a gateway installation. Wallet and SBP endpoints are served
+// under BaseURL in both Alfa installations.
+type Environment struct {
+	Name    string
+	BaseURL string // REST API, e.g. https://alfa.rbsuat.com/payment
+
+	// Production enables the guard against test cards and sandbox credentials.
+	Production bool
+}
+
+var (
+	// EnvSandbox is the Alfa test gateway (rbsuat).
+	EnvSandbox = Environment{
+		Name:    "sandbox",
+		BaseURL: DefaultBaseURL,
+	}
+
+	// EnvProduction is the Alfa production gateway.
+	EnvProduction = Environment{
+		Name:       "production",
+		BaseURL:    "https://pay.alfabank.ru/payment",
+		Production: true,
+	}
+)
+
+// sandboxHosts are hosts that only serve test installations.
+var sandboxHosts = []string{"rbsuat.com", "localhost", "127.0.0.1"}
+
+// testCards are the test card numbers of the sandbox and common card network test numbers.
+var testCards = map[string]bool{
+	"4111111111111111": true,
+	"4242424242424242": true,
+	"4012888888881881": true,
+	"5555555555555599": true,
+	"5555555555554444": true,
+	"5105105105105100": true,
+	"5000000000000009": true,
+	"2200000000000053": true,
+}
+
+// WithEnvironment selects a gateway environment. Options applied after it,
+// such as WithBaseURL, override individual settings.
+func WithEnvironment(env Environment) ClientOption {
+	return func(c *Client) {
+		c.env = env
+		if env.BaseURL != "" {
+			c.baseURL = strings.TrimRight(env.BaseURL, "/")
+		}
+	}
+}
+
+// Environment returns the environment the client was configured with.
+func (c *Client) Environment() Environment {
+	return c.env
+}
+
+// IsTestCard reports whether a card number is a known test card.
+func IsTestCard(pan string) bool {
+	pan = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, pan)
+	return testCards[pan]
+}
+
+// guardCredentials refuses sandbox credentials and hosts in a production environment.
+func (c *Client) guardCredentials(cred Credentials) error {
+	if !c.env.Production {
+		return nil
+	}
+	if u, err := url.Parse(c.baseURL); err == nil {
+		for _, host := range sandboxHosts {
+			if u.Hostname() == host || strings.HasSuffix(u.Hostname(), "."+host) {
+				return fmt.Errorf("%w: sandbox host %s", ErrTestDataInProduction, u.Hostname())
+			}
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(cred.UserName), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == '@'
+	}) {
+		switch word {
+		case "test", "sandbox", "demo", "uat":
+			return fmt.Errorf("%w: user name %q looks like a sandbox account", ErrTestDataInProduction, cred.UserName)
+		}
+	}
+	return nil
+}
+
+// guardParams refuses test card numbers in a production environment.
+func (c *Client) guardParams(params url.Values) error {
+	if c.env.Production && IsTestCard(params.Get("pan")) {
+		return fmt.Errorf("%w: test card number", ErrTestDataInProduction)
+	}
+	return nil
+}
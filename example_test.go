@@ -2,8 +2,11 @@ package alfapay_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/KlimGrishanov/alfapay"
@@ -315,3 +318,488 @@ func Example_orderWithCart() {
 
 	fmt.Printf("Order with cart registered: %s\n", resp.OrderID)
 }
+
+func Example_preAuthManager() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// Hold funds and let the gateway auto-reverse if we never capture
+	resp, err := client.Orders.RegisterPreAuth(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber:     "BOOKING-001",
+		Amount:          500000,
+		ReturnURL:       "https://your-site.com/payment/success",
+		AutoReverseDate: "2024-01-10T12:00:00",
+	})
+	if err != nil {
+		log.Fatalf("Failed to register pre-auth order: %v", err)
+	}
+
+	manager := alfapay.NewPreAuthManager(client, alfapay.WithPreAuthMaxAge(72*time.Hour))
+
+	// Once the customer has paid, resync the hold from the gateway
+	if _, err := manager.Sync(ctx, resp.OrderID); err != nil {
+		log.Fatalf("Failed to sync hold: %v", err)
+	}
+
+	// Warn about holds that expire within a day
+	for _, hold := range manager.Expiring(24 * time.Hour) {
+		fmt.Printf("Hold %s expires soon\n", hold.OrderNumber)
+	}
+
+	// Capture part of the hold on checkout
+	if _, err := manager.Capture(ctx, resp.OrderID, 450000); err != nil {
+		log.Fatalf("Failed to capture: %v", err)
+	}
+
+	// Reverse everything that exceeded the maximum age
+	if _, err := manager.ReverseExpired(ctx); err != nil {
+		log.Printf("Some holds were not reversed: %v", err)
+	}
+}
+
+func Example_registerIdempotent() {
+	client := alfapay.NewClient(
+		"your-username",
+		"your-password",
+		alfapay.WithPaymentPageURL("https://alfa.rbsuat.com/payment/merchants/your-merchant/payment_ru.html"),
+	)
+	ctx := context.Background()
+
+	// Safe to retry with the same order number after a timeout
+	resp, err := client.Orders.RegisterIdempotent(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-12347",
+		Amount:      100000,
+		ReturnURL:   "https://your-site.com/payment/success",
+	})
+	if errors.Is(err, alfapay.ErrOrderAmountMismatch) {
+		log.Fatalf("Order number reused with a different amount: %v", err)
+	}
+	if err != nil {
+		log.Fatalf("Failed to register order: %v", err)
+	}
+
+	fmt.Printf("Payment form URL: %s\n", resp.FormURL)
+}
+
+func Example_walletPayment() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// One code path for every wallet; the provider comes from the frontend
+	result, err := client.Wallets.Pay(ctx, alfapay.WalletYandexPay, &alfapay.WalletPayment{
+		Merchant:     "your-merchant-name",
+		OrderNumber:  "ORDER-W-001",
+		PaymentToken: "base64-encoded-wallet-token",
+		Amount:       150000,
+		IP:           "192.168.1.1",
+		ReturnURL:    "https://your-site.com/payment/success",
+	})
+	if err != nil {
+		log.Fatalf("Wallet payment failed: %v", err)
+	}
+
+	switch {
+	case !result.Success && result.Error != nil:
+		fmt.Printf("Wallet payment declined: %s\n", result.Error.Message)
+	case result.AcsURL != "":
+		fmt.Printf("Redirect customer to 3DS: %s\n", result.AcsURL)
+	default:
+		fmt.Printf("Wallet payment successful! Order ID: %s\n", result.OrderID)
+	}
+}
+
+func Example_googlePayToken() {
+	// Raw PaymentData JSON posted by the frontend after loadPaymentData()
+	raw := []byte(`{
+		"apiVersion": 2,
+		"apiVersionMinor": 0,
+		"paymentMethodData": {
+			"type": "CARD",
+			"description": "Visa •••• 1111",
+			"info": {
+				"cardNetwork": "VISA",
+				"cardDetails": "1111",
+				"assuranceDetails": {"accountVerified": true, "cardHolderAuthenticated": false}
+			},
+			"tokenizationData": {
+				"type": "PAYMENT_GATEWAY",
+				"token": "{\"signature\":\"MEQCIH6Q4OwQ0jAceFEkGF0JID6sJNXxOEi4r+mA7biRxqBQAiAondqoUpU/bdsrAOpZIsrHQS9nwiiNwOrr24RyPeHA0Q==\",\"intermediateSigningKey\":{\"signedKey\":\"{\\\"keyExpiration\\\":\\\"4102444800000\\\",\\\"keyValue\\\":\\\"MFkwEwYHKoZIzj0CAQ\\\"}\",\"signatures\":[\"MEYCIQCO2EIi48s8VTH+ilMEpoXLFfkxAwHjfPSCVED/QDSHmQIhALLJmrUlNAY8hDQRV/y1iKZGsWpeNmIP+z+tCQHQxP0v\"]},\"protocolVersion\":\"ECv2\",\"signedMessage\":\"{\\\"encryptedMessage\\\":\\\"PHxZxBQvVWwP\\\",\\\"ephemeralPublicKey\\\":\\\"BPhVspn70Zj2Kkgu9t8+ApEuUWsI\\\",\\\"tag\\\":\\\"TNwa3Q2WiyGi/eDA4XYVklq08KZiSxB7xvRiKK3H7kE=\\\"}\"}"
+			}
+		}
+	}`)
+
+	data, err := alfapay.ParseGooglePayPaymentData(raw)
+	if err != nil {
+		log.Fatalf("Invalid payment data: %v", err)
+	}
+	if err := data.Validate(alfapay.GooglePayValidation{AllowedCardNetworks: []string{"VISA", "MASTERCARD", "MIR"}}); err != nil {
+		log.Fatalf("Google Pay token rejected: %v", err)
+	}
+
+	fmt.Printf("Network: %s\n", data.CardNetwork())
+	fmt.Printf("Auth method: %s\n", data.AuthMethod())
+	fmt.Printf("3DS expected: %t\n", data.ThreeDSExpected())
+
+	// data.EncodedToken() goes into GooglePayRequest.PaymentToken
+
+	// Output:
+	// Network: VISA
+	// Auth method: PAN_ONLY
+	// 3DS expected: true
+}
+
+func Example_sbpPayload() {
+	// Static QR for an offline point of sale (QR code ID registered with NSPK)
+	static, err := alfapay.NewStaticSBPPayload("AS1000670LSS7DN18SJQDNP4B05KLJL2", "100000000008", 15000)
+	if err != nil {
+		log.Fatalf("Invalid static payload: %v", err)
+	}
+	link := static.String()
+	fmt.Println(link)
+
+	// Verify a payload before showing the code to the customer
+	payload, err := alfapay.ParseSBPPayload(link)
+	if err != nil {
+		log.Fatalf("Invalid SBP payload: %v", err)
+	}
+	if err := payload.VerifyAmount(15000, "RUB"); err != nil {
+		log.Fatalf("Unexpected amount: %v", err)
+	}
+	fmt.Printf("Type: %s, bank: %s, amount: %d %s\n", payload.Type, payload.BankID, payload.Amount, payload.Currency)
+
+	// Output:
+	// https://qr.nspk.ru/AS1000670LSS7DN18SJQDNP4B05KLJL2?type=01&bank=100000000008&sum=15000&cur=RUB&crc=BBFC
+	// Type: 01, bank: 100000000008, amount: 15000 RUB
+}
+
+func Example_sbpRefund() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// Check how much of an SBP QR order can still be refunded
+	summary, err := client.SBP.RefundSummary(ctx, "your-order-id")
+	if err != nil {
+		log.Fatalf("Failed to get refund summary: %v", err)
+	}
+	if summary.AmountsKnown && summary.RefundableAmount < 10000 {
+		log.Fatalf("Only %d left to refund", summary.RefundableAmount)
+	}
+
+	// SBP payments are refunded with refund.do, like card payments
+	resp, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: "your-order-id", Amount: 10000})
+	if err != nil {
+		log.Fatalf("Failed to refund SBP payment: %v", err)
+	}
+	if !resp.IsSuccess() {
+		log.Fatalf("SBP refund rejected: %s", resp.ErrorMessage)
+	}
+	fmt.Printf("Refunded %d of %d\n", summary.RefundedAmount+10000, summary.DepositedAmount)
+}
+
+func Example_sbpSubscription() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// Ask the customer to consent to a monthly SBP subscription
+	qr, err := client.SBP.GetSubscriptionQR(ctx, &alfapay.SBPSubscriptionQRRequest{
+		ClientID:            "customer-123",
+		SubscriptionPurpose: "Monthly subscription",
+	})
+	if err != nil {
+		log.Fatalf("Failed to get subscription QR: %v", err)
+	}
+
+	// After the customer scans the QR and consents in the banking app
+	sub, err := client.SBP.GetSubscriptionStatus(ctx, qr.QRID)
+	if err != nil {
+		log.Fatalf("Failed to get subscription status: %v", err)
+	}
+	if sub.Status != alfapay.SBPSubscriptionStatusActive {
+		fmt.Printf("Subscription is %s\n", sub.Status)
+		return
+	}
+
+	// Charge the SBP binding every month
+	resp, err := client.SBP.Recurrent(ctx, &alfapay.SBPRecurrentPaymentRequest{
+		OrderNumber: "SUB-SBP-12345-202311",
+		BindingID:   sub.BindingID,
+		Amount:      49900,
+		Description: "Monthly subscription payment",
+	})
+	if err != nil {
+		log.Fatalf("Failed to charge SBP binding: %v", err)
+	}
+
+	fmt.Printf("SBP recurrent payment %s: status %d\n", resp.OrderID, resp.OrderStatus)
+}
+
+func Example_sbpBankDirectory() {
+	banks := alfapay.DefaultSBPBankDirectory()
+
+	// Bank picker for B2C payouts: fuzzy search tolerates typos
+	for _, bank := range banks.Search("альфа", 3) {
+		fmt.Printf("%s %s\n", bank.MemberID, bank.Name)
+	}
+	for _, bank := range banks.Search("raifeisen", 3) {
+		fmt.Printf("%s %s\n", bank.MemberID, bank.NameEn)
+	}
+
+	// Resolve the member ID from a BIC
+	if bank, ok := banks.ByBIC("044525225"); ok {
+		fmt.Printf("%s %s\n", bank.MemberID, bank.Name)
+	}
+
+	// Output:
+	// 100000000008 Альфа-Банк
+	// 100000000007 Raiffeisenbank
+	// 100000000111 Сбербанк
+}
+
+func Example_sendPayout() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// Pay out to a seller by phone number; safe to retry with the same order number
+	result, err := client.Payouts.Send(ctx, &alfapay.PayoutRequest{
+		Payout: &alfapay.SBPB2CPayoutRequest{
+			OrderNumber: "PAYOUT-SELLER-42-20231101",
+			Amount:      1250000,
+			Purpose:     "Marketplace payout",
+			RecipientParams: &alfapay.SBPB2CRecipientParams{
+				BankID: "100000000111",
+				Phone:  "79001234567",
+				Name: &alfapay.SBPB2CRecipientName{
+					FirstName: "Иван",
+					LastName:  "Иванов",
+				},
+			},
+		},
+		Timeout: time.Minute,
+	})
+	if errors.Is(err, alfapay.ErrPayoutRecipientRejected) {
+		log.Fatalf("Recipient name does not match: %v", err)
+	}
+	if err != nil {
+		log.Fatalf("Payout failed: %v", err)
+	}
+
+	fmt.Printf("Payout %s to %s: %s\n", result.OrderID, result.RecipientName, result.Status)
+}
+
+func Example_sendPayoutBatch() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	items, err := alfapay.ParsePayoutCSV(strings.NewReader(`order_number,amount,phone,bank_id,first_name,last_name
+PAYOUT-2023-11-01-001,1250000,79001234567,100000000111,Иван,Иванов
+PAYOUT-2023-11-01-002,830000,79007654321,100000000004,Мария,Петрова
+`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Checkpoints survive a crash: rerunning with the same batch ID skips finished lines
+	store, err := alfapay.NewFilePayoutCheckpointStore("payout-checkpoints")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := client.Payouts.SendBatch(ctx, "payouts-2023-11-01", items, alfapay.PayoutBatchOptions{
+		Concurrency:   4,
+		RatePerSecond: 2,
+		Store:         store,
+	})
+	if err != nil {
+		log.Printf("Batch interrupted: %v", err)
+	}
+
+	fmt.Printf("Succeeded: %d, failed: %d, pending: %d\n", report.Succeeded, report.Failed, report.Pending)
+	report.WriteCSV(os.Stdout)
+}
+
+func Example_batchRefund() {
+	client := alfapay.NewClient("your-username", "your-password")
+
+	// Stop starting new refunds after ten minutes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	report, err := client.Refunds.BatchRefund(ctx, []alfapay.BatchRefundItem{
+		{OrderID: "70906e55-7114-41d6-8332-4609dc6590f4"},                // Full remaining amount
+		{OrderID: "a1b2c3d4-7114-41d6-8332-4609dc6590f4", Amount: 25000}, // Partial refund
+	}, alfapay.BatchRefundOptions{
+		Concurrency:           8,
+		SkipPartiallyRefunded: true, // Safe to rerun the same list
+	})
+	if err != nil {
+		log.Printf("Batch cancelled: %v", err)
+	}
+
+	for _, r := range report.Results {
+		if r.Outcome == alfapay.BatchRefundFailed {
+			fmt.Printf("Order %s: %s\n", r.OrderID, r.Reason)
+		}
+	}
+	fmt.Printf("Refunded %d orders for %d kopecks, skipped %d, failed %d\n",
+		report.Refunded, report.RefundedAmount, report.Skipped, report.Failed)
+}
+
+func Example_getManyStatuses() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	orderIDs := []string{
+		"70906e55-7114-41d6-8332-4609dc6590f4",
+		"a1b2c3d4-7114-41d6-8332-4609dc6590f4",
+	}
+
+	// At most 8 requests in flight; duplicate IDs are fetched once
+	results := client.Status.GetMany(ctx, orderIDs, 8)
+	for _, id := range orderIDs {
+		result := results[id]
+		if result.Err != nil {
+			log.Printf("Order %s: %v", id, result.Err)
+			continue
+		}
+		fmt.Printf("Order %s: status %d\n", id, result.Response.OrderStatus)
+	}
+}
+
+func Example_eachLastOrder() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	// All pages of the last 24 hours of orders, 200 per request
+	var total int64
+	err := client.Status.EachLastOrder(ctx, &alfapay.GetLastOrdersRequest{
+		FromTime: time.Now().Add(-24 * time.Hour),
+		ToTime:   time.Now(),
+		Size:     200,
+	}, func(order *alfapay.GetOrderStatusExtendedResponse) error {
+		if order.OrderStatus == alfapay.OrderStatusFullyAuthorized {
+			total += order.Amount
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to list orders: %v", err)
+	}
+	fmt.Printf("Paid in the last 24 hours: %d kopecks\n", total)
+}
+
+func Example_statusCache() {
+	client := alfapay.NewClient("your-username", "your-password",
+		alfapay.WithStatusCache(alfapay.StatusCacheOptions{
+			TTL:         5 * time.Second,
+			TerminalTTL: 10 * time.Minute,
+		}),
+	)
+	ctx := context.Background()
+
+	orderID := "70906e55-7114-41d6-8332-4609dc6590f4"
+
+	// The second call within five seconds is served from the cache
+	client.Status.GetByOrderID(ctx, orderID)
+	client.Status.GetByOrderID(ctx, orderID)
+
+	// Mutating calls drop the cached status, so the next lookup hits the gateway
+	client.Payments.Deposit(ctx, &alfapay.DepositRequest{OrderID: orderID, Amount: 100000})
+
+	// Drop it manually when a callback reports a change made elsewhere
+	client.Status.Invalidate(orderID)
+}
+
+func Example_times() {
+	status := &alfapay.GetOrderStatusExtendedResponse{
+		Date:          1698829200000,
+		DepositedDate: 1698829265000,
+	}
+	fmt.Println("Created:", status.CreatedAt().Format(time.RFC3339))
+	fmt.Println("Deposited:", status.DepositedAt().Format(time.RFC3339))
+	fmt.Println("Reversed:", status.ReversedAt().IsZero())
+
+	binding := &alfapay.Binding{ExpiryDate: "202712"}
+	expires, _ := binding.ExpiresAt()
+	fmt.Println("Binding expires:", expires.Format(time.RFC3339))
+
+	// Request times are sent in Moscow time
+	fmt.Println("From:", alfapay.FormatLastOrdersDate(time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Output:
+	// Created: 2023-11-01T12:00:00+03:00
+	// Deposited: 2023-11-01T12:01:05+03:00
+	// Reversed: true
+	// Binding expires: 2028-01-01T00:00:00+03:00
+	// From: 20231101030000
+}
+
+func Example_merchantRegistry() {
+	registry := alfapay.NewMerchantRegistry(alfapay.WithTimeout(20 * time.Second))
+
+	err := registry.Load([]alfapay.MerchantConfig{
+		{Key: "shop", UserName: "shop-api", Password: "shop-password"},
+		{Key: "shop-kids", Parent: "shop", MerchantLogin: "shop_kids"},
+		{Key: "travel", UserName: "travel-api", Password: "travel-password"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Merchants:", registry.Keys())
+
+	// Reload credentials from a file without restarting
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, "merchants.json", 30*time.Second, func(err error) {
+		log.Printf("Merchant config not reloaded: %v", err)
+	})
+
+	// Calls on a sub-merchant client carry its merchantLogin automatically
+	client, err := registry.Client("shop-kids")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_ = client
+
+	_, err = registry.Client("unknown")
+	fmt.Println(errors.Is(err, alfapay.ErrUnknownMerchant))
+
+	// Output:
+	// Merchants: [shop shop-kids travel]
+	// true
+}
+
+func Example_credentialsProvider() {
+	// The secrets file can be rewritten at any time; it is checked every 10 seconds
+	creds, err := alfapay.NewFileCredentialsProvider("/run/secrets/alfapay", 10*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := alfapay.NewClient("", "", alfapay.WithCredentialsProvider(creds))
+	ctx := context.Background()
+
+	// Requests rejected with new credentials are retried once with the previous ones
+	status, err := client.Status.GetByOrderID(ctx, "70906e55-7114-41d6-8332-4609dc6590f4")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Order status: %d\n", status.OrderStatus)
+}
+
+func Example_environment() {
+	client := alfapay.NewClient("shop-test-api", "password",
+		alfapay.WithEnvironment(alfapay.EnvProduction),
+	)
+	fmt.Println("Environment:", client.Environment().Name)
+
+	// Sandbox credentials are refused in production before any request is sent
+	_, err := client.Status.GetByOrderID(context.Background(), "70906e55-7114-41d6-8332-4609dc6590f4")
+	fmt.Println(errors.Is(err, alfapay.ErrTestDataInProduction))
+
+	fmt.Println(alfapay.IsTestCard("4111 1111 1111 1111"))
+
+	// Output:
+	// Environment: production
+	// true
+	// true
+}
@@ -0,0 +1,81 @@
+package export_test
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/export"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+// newPaidOrders starts a mock gateway with two paid orders, one partially refunded.
+func newPaidOrders() (*alfapay.Client, []string, func()) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{FeeRate: 0.02}))
+	client := alfapay.NewClient("test-api", "test", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	var ids []string
+	for _, number := range []string{"INV-1", "INV-2"} {
+		order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+			OrderNumber: number, Amount: 250000, ReturnURL: "https://shop.example.com/return",
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp, err := http.Post(srv.URL+"/admin/orders/"+order.OrderID+"/pay", "application/json",
+			strings.NewReader(`{"pan": "5555555555555599"}`))
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp.Body.Close()
+		ids = append(ids, order.OrderID)
+	}
+	if _, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: ids[1], Amount: 100000}); err != nil {
+		log.Fatal(err)
+	}
+	return client, ids, srv.Close
+}
+
+func ExampleLastOrders() {
+	client, _, done := newPaidOrders()
+	defer done()
+
+	w, err := export.NewWriter(os.Stdout, export.CSV,
+		"order_number", "amount", "currency", "status", "masked_pan", "refunded_amount", "refund_count", "fee_amount")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = export.LastOrders(context.Background(), client, &alfapay.GetLastOrdersRequest{
+		FromTime: time.Now().Add(-time.Hour),
+		ToTime:   time.Now(),
+	}, w)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// order_number,amount,currency,status,masked_pan,refunded_amount,refund_count,fee_amount
+	// INV-1,250000,643,2,555555**5599,0,0,5000
+	// INV-2,250000,643,2,555555**5599,100000,1,5000
+}
+
+func ExampleOrders() {
+	client, ids, done := newPaidOrders()
+	defer done()
+
+	w, err := export.NewWriter(os.Stdout, export.JSONL, "order_number", "payment_state", "refunded_amount", "bank_name")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := export.Orders(context.Background(), client, ids, w); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// {"order_number":"INV-1","payment_state":"DEPOSITED","refunded_amount":0,"bank_name":"MOCK BANK"}
+	// {"order_number":"INV-2","payment_state":"DEPOSITED","refunded_amount":100000,"bank_name":"MOCK BANK"}
+}
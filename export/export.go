@@ -0,0 +1,335 @@
+// Package export streams gateway orders to CSV or JSON Lines for finance reports.
+// Nested card, bank, amount and refund details are flattened into columns:
+//
+//	w, err := export.NewWriter(file, export.CSV, "order_number", "amount", "status", "masked_pan", "fee_amount")
+//	n, err := export.LastOrders(ctx, client, &alfapay.GetLastOrdersRequest{FromTime: from, ToTime: to}, w)
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	CSV   Format = "csv"   // Header row, then one row per order
+	JSONL Format = "jsonl" // One JSON object per order, keyed by column name
+)
+
+// DefaultColumns are exported when no columns are selected.
+var DefaultColumns = []string{
+	"order_id", "order_number", "created_at", "amount", "currency", "status", "payment_state",
+	"masked_pan", "approval_code", "deposited_amount", "refunded_amount", "fee_amount",
+}
+
+// column extracts a value from an order: a string, int64, bool or time.Time, or nil if absent.
+type column func(o *alfapay.GetOrderStatusExtendedResponse) interface{}
+
+// columns are the available columns. Amounts are in kopecks and times in Moscow time.
+var columns = map[string]column{
+	"order_id":                func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.OrderID() },
+	"order_number":            func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.OrderNumber },
+	"description":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.OrderDescription },
+	"created_at":              func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.CreatedAt() },
+	"authorized_at":           func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.AuthorizedAt() },
+	"deposited_at":            func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.DepositedAt() },
+	"refunded_at":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.RefundedAt() },
+	"reversed_at":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.ReversedAt() },
+	"amount":                  func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.Amount },
+	"currency":                func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.Currency },
+	"status":                  func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return int64(o.OrderStatus) },
+	"action_code":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return int64(o.ActionCode) },
+	"action_code_description": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.ActionCodeDescription },
+	"payment_way":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.PaymentWay },
+	"terminal_id":             func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.TerminalID },
+	"auth_ref_num":            func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.AuthRefNum },
+	"ip":                      func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.IP },
+	"chargeback":              func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return o.Chargeback },
+
+	"masked_pan":      cardColumn(func(c *alfapay.CardAuthInfo) interface{} { return c.MaskedPan }),
+	"expiration":      cardColumn(func(c *alfapay.CardAuthInfo) interface{} { return c.Expiration }),
+	"cardholder_name": cardColumn(func(c *alfapay.CardAuthInfo) interface{} { return c.CardholderName }),
+	"approval_code":   cardColumn(func(c *alfapay.CardAuthInfo) interface{} { return c.ApprovalCode }),
+	"eci": cardColumn(func(c *alfapay.CardAuthInfo) interface{} {
+		if c.SecureAuthInfo == nil {
+			return nil
+		}
+		return int64(c.SecureAuthInfo.Eci)
+	}),
+
+	"bank_name":         bankColumn(func(b *alfapay.BankInfo) interface{} { return b.BankName }),
+	"bank_country_code": bankColumn(func(b *alfapay.BankInfo) interface{} { return b.BankCountryCode }),
+	"bank_country_name": bankColumn(func(b *alfapay.BankInfo) interface{} { return b.BankCountryName }),
+
+	"payment_state":    amountColumn(func(a *alfapay.PaymentAmountInfo) interface{} { return a.PaymentState }),
+	"approved_amount":  amountColumn(func(a *alfapay.PaymentAmountInfo) interface{} { return a.ApprovedAmount }),
+	"deposited_amount": amountColumn(func(a *alfapay.PaymentAmountInfo) interface{} { return a.DepositedAmount }),
+	"refunded_amount":  refundedAmount,
+	"fee_amount":       amountColumn(func(a *alfapay.PaymentAmountInfo) interface{} { return a.FeeAmount }),
+
+	"binding_id": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.BindingInfo == nil {
+			return nil
+		}
+		return o.BindingInfo.BindingID
+	},
+	"client_id": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.BindingInfo == nil {
+			return nil
+		}
+		return o.BindingInfo.ClientID
+	},
+	"email": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.PayerData == nil {
+			return nil
+		}
+		return o.PayerData.Email
+	},
+	"phone": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.PayerData == nil {
+			return nil
+		}
+		return o.PayerData.Phone
+	},
+
+	"refund_count": func(o *alfapay.GetOrderStatusExtendedResponse) interface{} { return int64(len(o.Refunds)) },
+	"refund_ids":   refundsColumn(func(r *alfapay.Refund) string { return r.RefundID }),
+	"refund_amounts": refundsColumn(func(r *alfapay.Refund) string {
+		return strconv.FormatInt(r.RefundAmount, 10)
+	}),
+	"refund_dates": refundsColumn(func(r *alfapay.Refund) string { return formatTime(r.RefundedAt()) }),
+}
+
+func cardColumn(value func(*alfapay.CardAuthInfo) interface{}) column {
+	return func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.CardAuthInfo == nil {
+			return nil
+		}
+		return value(o.CardAuthInfo)
+	}
+}
+
+func bankColumn(value func(*alfapay.BankInfo) interface{}) column {
+	return func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.BankInfo == nil {
+			return nil
+		}
+		return value(o.BankInfo)
+	}
+}
+
+// amountColumn reads payment amount info. Orders without it, e.g. unpaid ones, have no amounts.
+func amountColumn(value func(*alfapay.PaymentAmountInfo) interface{}) column {
+	return func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		if o.PaymentAmountInfo == nil {
+			return nil
+		}
+		return value(o.PaymentAmountInfo)
+	}
+}
+
+// refundedAmount is the refunded total, summed from the refunds if the gateway did not report amount info.
+func refundedAmount(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+	if o.PaymentAmountInfo != nil {
+		return o.PaymentAmountInfo.RefundedAmount
+	}
+	var total int64
+	for _, refund := range o.Refunds {
+		total += refund.RefundAmount
+	}
+	return total
+}
+
+// refundsColumn joins a value of every refund with "; ", in refund order.
+func refundsColumn(value func(*alfapay.Refund) string) column {
+	return func(o *alfapay.GetOrderStatusExtendedResponse) interface{} {
+		values := make([]string, len(o.Refunds))
+		for i := range o.Refunds {
+			values[i] = value(&o.Refunds[i])
+		}
+		return strings.Join(values, "; ")
+	}
+}
+
+// Columns returns the names of all available columns.
+func Columns() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Writer writes orders as CSV rows or JSON lines. Call Flush when done.
+type Writer struct {
+	names   []string
+	columns []column
+
+	w           io.Writer
+	csv         *csv.Writer // Nil for JSONL
+	wroteHeader bool
+}
+
+// NewWriter creates a Writer for the given columns, or DefaultColumns if none are given.
+func NewWriter(w io.Writer, format Format, names ...string) (*Writer, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	ew := &Writer{names: names, w: w}
+	for _, name := range names {
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown export column %q", name)
+		}
+		ew.columns = append(ew.columns, col)
+	}
+
+	switch format {
+	case CSV:
+		ew.csv = csv.NewWriter(w)
+	case JSONL:
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	return ew, nil
+}
+
+// Write writes an order. A CSV header is written before the first order.
+func (w *Writer) Write(order *alfapay.GetOrderStatusExtendedResponse) error {
+	if w.csv == nil {
+		// Keys follow the column order, which a map would lose
+		var b bytes.Buffer
+		b.WriteByte('{')
+		for i, col := range w.columns {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(w.names[i])
+			value, err := json.Marshal(jsonValue(col(order)))
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteString("}\n")
+		_, err := w.w.Write(b.Bytes())
+		return err
+	}
+
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.csv.Write(w.names); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		record[i] = csvValue(col(order))
+	}
+	return w.csv.Write(record)
+}
+
+// Flush writes buffered data, including the CSV header if no order was written.
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.csv.Write(w.names); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return formatTime(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonValue converts times to strings; zero times become null.
+func jsonValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		if t.IsZero() {
+			return nil
+		}
+		return formatTime(t)
+	}
+	return v
+}
+
+// formatTime formats a time as RFC 3339 in Moscow time; zero times are empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(alfapay.MoscowTime).Format(time.RFC3339)
+}
+
+// LastOrders exports the orders matching req, fetching all pages, and flushes w.
+// It returns the number of orders written.
+func LastOrders(ctx context.Context, client *alfapay.Client, req *alfapay.GetLastOrdersRequest, w *Writer) (int, error) {
+	n := 0
+	err := client.Status.EachLastOrder(ctx, req, func(order *alfapay.GetOrderStatusExtendedResponse) error {
+		n++
+		return w.Write(order)
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, w.Flush()
+}
+
+// OrdersBatchSize is the number of statuses Orders fetches in parallel before writing them.
+const OrdersBatchSize = 50
+
+// Orders exports orders by ID in the given order and flushes w. Statuses are fetched
+// OrdersBatchSize at a time with Status.GetMany. It stops at the first order that cannot
+// be fetched and returns the number of orders written.
+func Orders(ctx context.Context, client *alfapay.Client, ids []string, w *Writer) (int, error) {
+	n := 0
+	for start := 0; start < len(ids); start += OrdersBatchSize {
+		batch := ids[start:min(start+OrdersBatchSize, len(ids))]
+		results := client.Status.GetMany(ctx, batch, 0)
+		for _, id := range batch {
+			result := results[id]
+			if result.Err != nil {
+				return n, fmt.Errorf("failed to export order %s: %w", id, result.Err)
+			}
+			if err := w.Write(result.Response); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, w.Flush()
+}
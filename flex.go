@@ -0,0 +1,430 @@
+package alfapay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The gateway is inconsistent about JSON types: errorCode may be a string or a number,
+// orderStatus an int or a numeric string, and booleans may arrive as "true"/"false".
+// The Flex types below decode any of these representations; response structs use them
+// as shadow fields in UnmarshalJSON, so their own fields keep plain Go types.
+
+// FlexString is a string that also decodes from JSON numbers and booleans.
+type FlexString string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || string(data) == "null":
+		return nil
+	case data[0] == '"':
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*s = FlexString(v)
+	case data[0] == '{' || data[0] == '[':
+		return fmt.Errorf("alfapay: cannot decode %s into string", data)
+	default:
+		// Numbers and booleans are kept in their literal form.
+		*s = FlexString(data)
+	}
+	return nil
+}
+
+// String returns the value as a string.
+func (s FlexString) String() string {
+	return string(s)
+}
+
+// FlexInt is an integer that also decodes from numeric JSON strings.
+// Empty strings and null decode as zero.
+type FlexInt int64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	v, err := parseFlexInt(data)
+	if err != nil {
+		return err
+	}
+	*i = FlexInt(v)
+	return nil
+}
+
+// Int returns the value as an int.
+func (i FlexInt) Int() int {
+	return int(i)
+}
+
+// FlexBool is a boolean that also decodes from "true"/"false", "1"/"0" and 1/0.
+// Empty strings and null decode as false.
+type FlexBool bool
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	raw, err := unquoteFlex(data)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(raw) {
+	case "", "null", "false", "0", "n", "no":
+		*b = false
+	case "true", "1", "y", "yes":
+		*b = true
+	default:
+		return fmt.Errorf("alfapay: cannot decode %s into bool", data)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting both 2 and "2".
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	v, err := parseFlexInt(data)
+	if err != nil {
+		return err
+	}
+	*s = OrderStatus(v)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding ActionCode and Chargeback leniently.
+func (r *GetOrderStatusExtendedResponse) UnmarshalJSON(data []byte) error {
+	type plain GetOrderStatusExtendedResponse
+	aux := struct {
+		*plain
+		ActionCode FlexInt  `json:"actionCode,omitempty"`
+		Chargeback FlexBool `json:"chargeback,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.ActionCode = int(aux.ActionCode)
+	r.Chargeback = bool(aux.Chargeback)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Eci leniently.
+func (r *SecureAuthInfo) UnmarshalJSON(data []byte) error {
+	type plain SecureAuthInfo
+	aux := struct {
+		*plain
+		Eci FlexInt `json:"eci,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Eci = int(aux.Eci)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding IsExpired leniently.
+func (r *Binding) UnmarshalJSON(data []byte) error {
+	type plain Binding
+	aux := struct {
+		*plain
+		IsExpired FlexBool `json:"isExpired,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.IsExpired = bool(aux.IsExpired)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding TotalCount and Page and PageSize leniently.
+func (r *GetLastOrdersResponse) UnmarshalJSON(data []byte) error {
+	type plain GetLastOrdersResponse
+	aux := struct {
+		*plain
+		TotalCount FlexInt `json:"totalCount,omitempty"`
+		Page       FlexInt `json:"page,omitempty"`
+		PageSize   FlexInt `json:"pageSize,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.TotalCount = int(aux.TotalCount)
+	r.Page = int(aux.Page)
+	r.PageSize = int(aux.PageSize)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *RecurrentPaymentResponse) UnmarshalJSON(data []byte) error {
+	type plain RecurrentPaymentResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *ApplePayPaymentResponse) UnmarshalJSON(data []byte) error {
+	type plain ApplePayPaymentResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *GooglePayResponse) UnmarshalJSON(data []byte) error {
+	type plain GooglePayResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *SamsungPayPaymentResponse) UnmarshalJSON(data []byte) error {
+	type plain SamsungPayPaymentResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *MirPayResponse) UnmarshalJSON(data []byte) error {
+	type plain MirPayResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Success leniently.
+func (r *YandexPayResponse) UnmarshalJSON(data []byte) error {
+	type plain YandexPayResponse
+	aux := struct {
+		*plain
+		Success FlexBool `json:"success"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Success = bool(aux.Success)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *RecurrentPaymentError) UnmarshalJSON(data []byte) error {
+	type plain RecurrentPaymentError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *ApplePayError) UnmarshalJSON(data []byte) error {
+	type plain ApplePayError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *GooglePayError) UnmarshalJSON(data []byte) error {
+	type plain GooglePayError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *SamsungPayError) UnmarshalJSON(data []byte) error {
+	type plain SamsungPayError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *MirPayError) UnmarshalJSON(data []byte) error {
+	type plain MirPayError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *YandexPayError) UnmarshalJSON(data []byte) error {
+	type plain YandexPayError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Code leniently.
+func (r *WalletError) UnmarshalJSON(data []byte) error {
+	type plain WalletError
+	aux := struct {
+		*plain
+		Code FlexInt `json:"code,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Code = int(aux.Code)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding OrderStatus leniently.
+func (r *SBPB2BPerformResponse) UnmarshalJSON(data []byte) error {
+	type plain SBPB2BPerformResponse
+	aux := struct {
+		*plain
+		OrderStatus FlexString `json:"orderStatus,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.OrderStatus = string(aux.OrderStatus)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding OrderStatus leniently.
+func (r *SBPB2CPayoutResponse) UnmarshalJSON(data []byte) error {
+	type plain SBPB2CPayoutResponse
+	aux := struct {
+		*plain
+		OrderStatus FlexString `json:"orderStatus,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.OrderStatus = string(aux.OrderStatus)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding OrderStatus leniently.
+func (r *SBPB2CCheckPayoutResponse) UnmarshalJSON(data []byte) error {
+	type plain SBPB2CCheckPayoutResponse
+	aux := struct {
+		*plain
+		OrderStatus FlexString `json:"orderStatus,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.OrderStatus = string(aux.OrderStatus)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding OrderStatus leniently.
+func (r *SBPB2CPayoutStatusResponse) UnmarshalJSON(data []byte) error {
+	type plain SBPB2CPayoutStatusResponse
+	aux := struct {
+		*plain
+		OrderStatus FlexString `json:"orderStatus,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.OrderStatus = string(aux.OrderStatus)
+	return nil
+}
+
+// parseFlexInt decodes an integer from a JSON number or string.
+// Integral floats such as 2.0 are accepted, as some endpoints send them.
+func parseFlexInt(data []byte) (int64, error) {
+	raw, err := unquoteFlex(data)
+	if err != nil {
+		return 0, err
+	}
+	if raw == "" || raw == "null" {
+		return 0, nil
+	}
+	if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) >= 1<<63 {
+		return 0, fmt.Errorf("alfapay: cannot decode %s into integer", data)
+	}
+	return int64(f), nil
+}
+
+// unquoteFlex returns the contents of a JSON string, or the literal of any other scalar.
+func unquoteFlex(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(v), nil
+	}
+	if len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+		return "", fmt.Errorf("alfapay: unexpected %s", data)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,198 @@
+package alfapay_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// responseTargets are the response types decoded from the recorded samples in testdata/responses.
+var responseTargets = []func() interface{}{
+	func() interface{} { return new(alfapay.BaseResponse) },
+	func() interface{} { return new(alfapay.GetOrderStatusExtendedResponse) },
+	func() interface{} { return new(alfapay.GetLastOrdersResponse) },
+	func() interface{} { return new(alfapay.GetBindingsResponse) },
+	func() interface{} { return new(alfapay.ApplePayPaymentResponse) },
+	func() interface{} { return new(alfapay.SBPB2BPerformResponse) },
+	func() interface{} { return new(alfapay.SBPB2CPayoutStatusResponse) },
+}
+
+func recordedSamples(tb testing.TB) map[string][]byte {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "responses", "*.json"))
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("no recorded samples: %v", err)
+	}
+	samples := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		samples[filepath.Base(file)] = data
+	}
+	return samples
+}
+
+func TestDecodeRecordedSamples(t *testing.T) {
+	samples := recordedSamples(t)
+
+	decode := func(name string, v interface{}) {
+		t.Helper()
+		if err := json.Unmarshal(samples[name], v); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	var status alfapay.GetOrderStatusExtendedResponse
+	decode("order_status_numeric_codes.json", &status)
+	if !status.IsSuccess() || status.OrderStatus != alfapay.OrderStatusRefunded || !status.Chargeback ||
+		status.CardAuthInfo.SecureAuthInfo.Eci != 5 {
+		t.Errorf("numeric codes decoded as %+v", status)
+	}
+
+	var notFound alfapay.BaseResponse
+	decode("order_not_found.json", &notFound)
+	if notFound.IsSuccess() || notFound.ErrorCode != "6" {
+		t.Errorf("errorCode 6 decoded as %q", notFound.ErrorCode)
+	}
+
+	var b2b alfapay.SBPB2BPerformResponse
+	decode("sbp_b2b_numeric_status.json", &b2b)
+	if b2b.OrderStatus != "1" {
+		t.Errorf("numeric orderStatus decoded as %q", b2b.OrderStatus)
+	}
+
+	var applePay alfapay.ApplePayPaymentResponse
+	decode("applepay_string_bool.json", &applePay)
+	if applePay.Success || applePay.Error.Code != 10 {
+		t.Errorf("string success decoded as %+v", applePay)
+	}
+
+	var bindings alfapay.GetBindingsResponse
+	decode("bindings_string_bool.json", &bindings)
+	if len(bindings.Bindings) != 1 || bindings.Bindings[0].IsExpired {
+		t.Errorf("string isExpired decoded as %+v", bindings.Bindings)
+	}
+
+	var orders alfapay.GetLastOrdersResponse
+	decode("last_orders_string_counts.json", &orders)
+	if orders.TotalCount != 2 || len(orders.Orders) != 2 || orders.Orders[1].OrderStatus != alfapay.OrderStatusDeclined {
+		t.Errorf("string counts decoded as %+v", orders)
+	}
+}
+
+func TestDecodeLenientFields(t *testing.T) {
+	var payment alfapay.RecurrentPaymentResponse
+	if err := json.Unmarshal([]byte(`{"success":"true","data":{"orderId":"o-1"},"error":{"code":"5","message":"m"}}`), &payment); err != nil {
+		t.Fatal(err)
+	}
+	if !payment.Success || payment.Data.OrderID != "o-1" || payment.Error.Code != 5 || payment.Error.Message != "m" {
+		t.Errorf("recurrent payment decoded as %+v", payment)
+	}
+
+	var status alfapay.GetOrderStatusExtendedResponse
+	if err := json.Unmarshal([]byte(`{"errorCode":0,"orderNumber":"n-1","actionCode":"-2007","chargeback":1}`), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.IsSuccess() || status.OrderNumber != "n-1" || status.ActionCode != -2007 || !status.Chargeback {
+		t.Errorf("order status decoded as %+v", status)
+	}
+
+	var payout alfapay.SBPB2CPayoutStatusResponse
+	if err := json.Unmarshal([]byte(`{"orderId":"p-1","orderStatus":2}`), &payout); err != nil {
+		t.Fatal(err)
+	}
+	if payout.OrderID != "p-1" || payout.OrderStatus != "2" {
+		t.Errorf("payout status decoded as %+v", payout)
+	}
+
+	var bad alfapay.MirPayResponse
+	if err := json.Unmarshal([]byte(`{"success":"maybe"}`), &bad); err == nil {
+		t.Error("invalid success decoded without error")
+	}
+}
+
+func FuzzDecodeResponse(f *testing.F) {
+	for _, data := range recordedSamples(f) {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, target := range responseTargets {
+			// Must not panic on any input.
+			_ = json.Unmarshal(data, target())
+		}
+	})
+}
+
+func FuzzFlexInt(f *testing.F) {
+	for _, seed := range []string{`0`, `2`, `"2"`, `" 6 "`, `-2007`, `2.0`, `""`, `null`, `"abc"`, `1e3`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var v alfapay.FlexInt
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again alfapay.FlexInt
+		if err := json.Unmarshal(encoded, &again); err != nil || again != v {
+			t.Fatalf("%s decoded as %d, round trip gave %d (%v)", data, v, again, err)
+		}
+
+		var status alfapay.OrderStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil || int64(status) != int64(v) {
+			t.Fatalf("%s: OrderStatus %d, FlexInt %d (%v)", data, status, v, err)
+		}
+	})
+}
+
+func FuzzFlexString(f *testing.F) {
+	for _, seed := range []string{`"0"`, `0`, `6`, `"Заказ не найден"`, `true`, `null`, `{}`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var v alfapay.FlexString
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again alfapay.FlexString
+		if err := json.Unmarshal(encoded, &again); err != nil || again != v {
+			t.Fatalf("%s decoded as %q, round trip gave %q (%v)", data, v, again, err)
+		}
+	})
+}
+
+func FuzzFlexBool(f *testing.F) {
+	for _, seed := range []string{`true`, `false`, `"true"`, `"false"`, `"1"`, `0`, `""`, `null`, `"maybe"`} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var v alfapay.FlexBool
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again alfapay.FlexBool
+		if err := json.Unmarshal(encoded, &again); err != nil || again != v {
+			t.Fatalf("%s decoded as %v, round trip gave %v (%v)", data, v, again, err)
+		}
+	})
+}
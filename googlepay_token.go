@@ -0,0 +1,485 @@
+package alfapay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GooglePayAuthMethod represents the Google Pay card authentication method.
+type GooglePayAuthMethod string
+
+const (
+	GooglePayAuthPANOnly       GooglePayAuthMethod = "PAN_ONLY"       // Card on file, 3DS may be required
+	GooglePayAuthCryptogram3DS GooglePayAuthMethod = "CRYPTOGRAM_3DS" // Device token, authenticated by the device
+	GooglePayAuthUnknown       GooglePayAuthMethod = ""               // Assurance details were not requested
+)
+
+// ErrGooglePayTokenExpired is returned when the token's intermediate signing key or the message has expired.
+var ErrGooglePayTokenExpired = errors.New("google pay token has expired")
+
+// ErrGooglePaySignature is returned when the token signatures do not verify for the expected recipient.
+var ErrGooglePaySignature = errors.New("google pay token signature is invalid")
+
+// googlePaySenderID is the sender ID signed into every Google Pay token.
+const googlePaySenderID = "Google"
+
+// GooglePayPaymentData represents the PaymentData object returned by the Google Pay API on the frontend.
+type GooglePayPaymentData struct {
+	APIVersion        int                        `json:"apiVersion"`
+	APIVersionMinor   int                        `json:"apiVersionMinor"`
+	Email             string                     `json:"email,omitempty"`
+	PaymentMethodData GooglePayPaymentMethodData `json:"paymentMethodData"`
+}
+
+// GooglePayPaymentMethodData represents the payment method returned by Google Pay.
+type GooglePayPaymentMethodData struct {
+	Type             string                    `json:"type"`
+	Description      string                    `json:"description,omitempty"`
+	Info             GooglePayCardInfo         `json:"info"`
+	TokenizationData GooglePayTokenizationData `json:"tokenizationData"`
+}
+
+// GooglePayCardInfo represents card information returned by Google Pay.
+type GooglePayCardInfo struct {
+	CardNetwork      string                     `json:"cardNetwork,omitempty"`
+	CardDetails      string                     `json:"cardDetails,omitempty"`
+	AssuranceDetails *GooglePayAssuranceDetails `json:"assuranceDetails,omitempty"`
+}
+
+// GooglePayAssuranceDetails represents the verification performed by Google Pay.
+type GooglePayAssuranceDetails struct {
+	AccountVerified         bool `json:"accountVerified"`
+	CardHolderAuthenticated bool `json:"cardHolderAuthenticated"`
+}
+
+// GooglePayTokenizationData represents the tokenized payment credentials.
+type GooglePayTokenizationData struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// GooglePayToken represents the decoded payment token (the signed, encrypted envelope).
+type GooglePayToken struct {
+	ProtocolVersion        string                           `json:"protocolVersion"`
+	Signature              string                           `json:"signature"`
+	IntermediateSigningKey *GooglePayIntermediateSigningKey `json:"intermediateSigningKey,omitempty"`
+	SignedMessage          string                           `json:"signedMessage"`
+}
+
+// GooglePayIntermediateSigningKey represents the ECv2 intermediate signing key.
+type GooglePayIntermediateSigningKey struct {
+	SignedKey  string   `json:"signedKey"`
+	Signatures []string `json:"signatures"`
+}
+
+// GooglePayMessage represents the decrypted signedMessage of a Google Pay token.
+type GooglePayMessage struct {
+	GatewayMerchantID    string                        `json:"gatewayMerchantId,omitempty"`
+	MessageExpiration    string                        `json:"messageExpiration"` // Unix time in milliseconds
+	MessageID            string                        `json:"messageId"`
+	PaymentMethod        string                        `json:"paymentMethod"`
+	PaymentMethodDetails GooglePayPaymentMethodDetails `json:"paymentMethodDetails"`
+}
+
+// GooglePayPaymentMethodDetails represents the card credentials of a decrypted Google Pay message.
+type GooglePayPaymentMethodDetails struct {
+	AuthMethod      GooglePayAuthMethod `json:"authMethod"`
+	PAN             string              `json:"pan"`
+	ExpirationMonth int                 `json:"expirationMonth"`
+	ExpirationYear  int                 `json:"expirationYear"`
+	Cryptogram      string              `json:"cryptogram,omitempty"`   // CRYPTOGRAM_3DS only
+	ECIIndicator    string              `json:"eciIndicator,omitempty"` // CRYPTOGRAM_3DS only
+}
+
+// GooglePayValidation configures Google Pay token pre-validation.
+//
+// With GatewayID or RecipientID set, the token signatures are verified against
+// RootSigningKeys, which proves the token was issued by Google for that recipient.
+//
+// The message expiration and gateway merchant ID are only present in the encrypted message,
+// so they are checked only when DecryptionKey is set. That requires the DIRECT tokenization
+// type, since PAYMENT_GATEWAY tokens can only be decrypted by the gateway. Without DecryptionKey,
+// only the expiration of the intermediate signing key is checked and an expired message passes.
+type GooglePayValidation struct {
+	ProtocolVersions    []string // Default: ECv2
+	AllowedCardNetworks []string // Empty allows any network
+	Now                 func() time.Time
+
+	GatewayID       string             // Expected gatewayId; the signed recipient is "gateway:<GatewayID>"
+	RecipientID     string             // Expected signed recipient, e.g. "merchant:<merchantId>"; overrides GatewayID
+	RootSigningKeys []*ecdsa.PublicKey // Google's ECv2 root signing keys, see ParseGooglePayRootKeys
+
+	DecryptionKey     *ecdh.PrivateKey // P-256 key registered with Google for DIRECT tokenization; enables the message checks
+	GatewayMerchantID string           // Expected gatewayMerchantId; requires DecryptionKey
+}
+
+// ParseGooglePayPaymentData parses the raw PaymentData JSON from the frontend.
+func ParseGooglePayPaymentData(data []byte) (*GooglePayPaymentData, error) {
+	var pd GooglePayPaymentData
+	if err := json.Unmarshal(data, &pd); err != nil {
+		return nil, fmt.Errorf("failed to decode google pay payment data: %w", err)
+	}
+	if pd.PaymentMethodData.TokenizationData.Token == "" {
+		return nil, errors.New("google pay payment data has no token")
+	}
+	return &pd, nil
+}
+
+// Token decodes the payment token envelope.
+func (d *GooglePayPaymentData) Token() (*GooglePayToken, error) {
+	var token GooglePayToken
+	if err := json.Unmarshal([]byte(d.PaymentMethodData.TokenizationData.Token), &token); err != nil {
+		return nil, fmt.Errorf("failed to decode google pay token: %w", err)
+	}
+	return &token, nil
+}
+
+// EncodedToken returns the token base64-encoded, as expected by GooglePayRequest.PaymentToken.
+func (d *GooglePayPaymentData) EncodedToken() string {
+	return base64.StdEncoding.EncodeToString([]byte(d.PaymentMethodData.TokenizationData.Token))
+}
+
+// CardNetwork returns the card network (VISA, MASTERCARD, MIR, ...).
+func (d *GooglePayPaymentData) CardNetwork() string {
+	return d.PaymentMethodData.Info.CardNetwork
+}
+
+// AuthMethod returns the card authentication method as a best guess.
+// The method itself is only present in the encrypted message, so it is inferred from
+// assurance details: a device-token payment authenticates the cardholder, but a card on file
+// may be authenticated too. GooglePayAuthUnknown is returned when they were not requested.
+// The authoritative value is GooglePayMessage.PaymentMethodDetails.AuthMethod, see GooglePayToken.Decrypt.
+func (d *GooglePayPaymentData) AuthMethod() GooglePayAuthMethod {
+	details := d.PaymentMethodData.Info.AssuranceDetails
+	if details == nil {
+		return GooglePayAuthUnknown
+	}
+	if details.CardHolderAuthenticated {
+		return GooglePayAuthCryptogram3DS
+	}
+	return GooglePayAuthPANOnly
+}
+
+// ThreeDSExpected reports whether the payment may require a 3DS redirect.
+func (d *GooglePayPaymentData) ThreeDSExpected() bool {
+	return d.AuthMethod() != GooglePayAuthCryptogram3DS
+}
+
+// Validate checks the payment data and token envelope before sending it to the gateway.
+// The message expiration and gateway merchant ID are checked only with opts.DecryptionKey.
+func (d *GooglePayPaymentData) Validate(opts GooglePayValidation) error {
+	if d.APIVersion != 2 {
+		return fmt.Errorf("unsupported google pay api version: %d", d.APIVersion)
+	}
+	if d.PaymentMethodData.Type != "CARD" {
+		return fmt.Errorf("unsupported google pay payment method: %q", d.PaymentMethodData.Type)
+	}
+	tokenization := d.PaymentMethodData.TokenizationData.Type
+	if tokenization != "PAYMENT_GATEWAY" && (opts.DecryptionKey == nil || tokenization != "DIRECT") {
+		return fmt.Errorf("unsupported google pay tokenization type: %q", d.PaymentMethodData.TokenizationData.Type)
+	}
+	if len(opts.AllowedCardNetworks) > 0 && !containsFold(opts.AllowedCardNetworks, d.CardNetwork()) {
+		return fmt.Errorf("card network %q is not allowed", d.CardNetwork())
+	}
+
+	token, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	versions := opts.ProtocolVersions
+	if len(versions) == 0 {
+		versions = []string{"ECv2"}
+	}
+	if !containsFold(versions, token.ProtocolVersion) {
+		return fmt.Errorf("unsupported google pay protocol version: %q", token.ProtocolVersion)
+	}
+	if token.Signature == "" || token.SignedMessage == "" {
+		return errors.New("google pay token is missing signature or signed message")
+	}
+
+	var message struct {
+		EncryptedMessage   string `json:"encryptedMessage"`
+		EphemeralPublicKey string `json:"ephemeralPublicKey"`
+		Tag                string `json:"tag"`
+	}
+	if err := json.Unmarshal([]byte(token.SignedMessage), &message); err != nil {
+		return fmt.Errorf("failed to decode google pay signed message: %w", err)
+	}
+	if message.EncryptedMessage == "" || message.EphemeralPublicKey == "" || message.Tag == "" {
+		return errors.New("google pay signed message is incomplete")
+	}
+
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	if token.ProtocolVersion == "ECv2" {
+		if token.IntermediateSigningKey == nil || len(token.IntermediateSigningKey.Signatures) == 0 {
+			return errors.New("google pay token is missing the intermediate signing key")
+		}
+
+		var key struct {
+			KeyValue      string `json:"keyValue"`
+			KeyExpiration string `json:"keyExpiration"`
+		}
+		if err := json.Unmarshal([]byte(token.IntermediateSigningKey.SignedKey), &key); err != nil {
+			return fmt.Errorf("failed to decode google pay signed key: %w", err)
+		}
+		expiration, err := strconv.ParseInt(key.KeyExpiration, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid google pay key expiration: %q", key.KeyExpiration)
+		}
+		if !now().Before(time.UnixMilli(expiration)) {
+			return ErrGooglePayTokenExpired
+		}
+	}
+
+	recipient := opts.RecipientID
+	if recipient == "" && opts.GatewayID != "" {
+		recipient = "gateway:" + opts.GatewayID
+	}
+	if recipient != "" {
+		if err := token.verify(recipient, opts.RootSigningKeys); err != nil {
+			return err
+		}
+	}
+
+	if opts.DecryptionKey == nil {
+		if opts.GatewayMerchantID != "" {
+			return errors.New("google pay gateway merchant ID can only be checked with a decryption key")
+		}
+		return nil
+	}
+
+	decrypted, err := token.Decrypt(opts.DecryptionKey)
+	if err != nil {
+		return err
+	}
+	expiration, err := strconv.ParseInt(decrypted.MessageExpiration, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid google pay message expiration: %q", decrypted.MessageExpiration)
+	}
+	if !now().Before(time.UnixMilli(expiration)) {
+		return ErrGooglePayTokenExpired
+	}
+	if opts.GatewayMerchantID != "" && decrypted.GatewayMerchantID != opts.GatewayMerchantID {
+		return fmt.Errorf("google pay token was issued for gateway merchant %q", decrypted.GatewayMerchantID)
+	}
+	return nil
+}
+
+// verify checks the ECv2 signature chain: a root key signs the intermediate key,
+// which signs the message for the recipient.
+func (t *GooglePayToken) verify(recipientID string, rootKeys []*ecdsa.PublicKey) error {
+	if t.ProtocolVersion != "ECv2" {
+		return fmt.Errorf("google pay signature verification is not supported for %q", t.ProtocolVersion)
+	}
+	if len(rootKeys) == 0 {
+		return errors.New("google pay root signing keys are required to verify the recipient")
+	}
+	signedKey := t.IntermediateSigningKey.SignedKey
+
+	verified := false
+	keyDigest := sha256.Sum256(googlePaySignedBytes(googlePaySenderID, t.ProtocolVersion, signedKey))
+	for _, encoded := range t.IntermediateSigningKey.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		for _, root := range rootKeys {
+			if ecdsa.VerifyASN1(root, keyDigest[:], sig) {
+				verified = true
+			}
+		}
+	}
+	if !verified {
+		return fmt.Errorf("%w: intermediate signing key", ErrGooglePaySignature)
+	}
+
+	var key struct {
+		KeyValue string `json:"keyValue"`
+	}
+	if err := json.Unmarshal([]byte(signedKey), &key); err != nil {
+		return fmt.Errorf("failed to decode google pay signed key: %w", err)
+	}
+	intermediate, err := parseGooglePayPublicKey(key.KeyValue)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(t.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGooglePaySignature, err)
+	}
+	messageDigest := sha256.Sum256(googlePaySignedBytes(googlePaySenderID, recipientID, t.ProtocolVersion, t.SignedMessage))
+	if !ecdsa.VerifyASN1(intermediate, messageDigest[:], sig) {
+		return fmt.Errorf("%w: message is not signed for %s", ErrGooglePaySignature, recipientID)
+	}
+	return nil
+}
+
+// Decrypt decrypts the signedMessage of an ECv2 token with the recipient's private key.
+// It does not verify signatures or expiration; use Validate for that.
+func (t *GooglePayToken) Decrypt(key *ecdh.PrivateKey) (*GooglePayMessage, error) {
+	if t.ProtocolVersion != "ECv2" {
+		return nil, fmt.Errorf("google pay decryption is not supported for %q", t.ProtocolVersion)
+	}
+
+	var signed struct {
+		EncryptedMessage   string `json:"encryptedMessage"`
+		EphemeralPublicKey string `json:"ephemeralPublicKey"`
+		Tag                string `json:"tag"`
+	}
+	if err := json.Unmarshal([]byte(t.SignedMessage), &signed); err != nil {
+		return nil, fmt.Errorf("failed to decode google pay signed message: %w", err)
+	}
+	ephemeral, err := base64.StdEncoding.DecodeString(signed.EphemeralPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay ephemeral public key: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(signed.EncryptedMessage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay encrypted message: %w", err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(signed.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay message tag: %w", err)
+	}
+
+	peer, err := ecdh.P256().NewPublicKey(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay ephemeral public key: %w", err)
+	}
+	shared, err := key.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive google pay shared secret: %w", err)
+	}
+
+	// ECIES-KEM: HKDF-SHA256 over the ephemeral key and shared secret yields
+	// a 256-bit AES-CTR key followed by a 256-bit HMAC-SHA256 key.
+	keys := hkdfSHA256(append(ephemeral, shared...), make([]byte, sha256.Size), []byte(googlePaySenderID), 64)
+
+	mac := hmac.New(sha256.New, keys[32:])
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("%w: message tag does not match", ErrGooglePaySignature)
+	}
+
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(plaintext, ciphertext)
+
+	var message GooglePayMessage
+	if err := json.Unmarshal(plaintext, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode google pay message: %w", err)
+	}
+	return &message, nil
+}
+
+// ParseGooglePayRootKeys parses Google's root signing keys (keys.json from
+// https://payments.developers.google.com/paymentmethodtoken/keys.json), keeping unexpired ECv2 keys.
+func ParseGooglePayRootKeys(data []byte) ([]*ecdsa.PublicKey, error) {
+	var doc struct {
+		Keys []struct {
+			KeyValue        string `json:"keyValue"`
+			ProtocolVersion string `json:"protocolVersion"`
+			KeyExpiration   string `json:"keyExpiration"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode google pay root keys: %w", err)
+	}
+
+	var keys []*ecdsa.PublicKey
+	for _, k := range doc.Keys {
+		if k.ProtocolVersion != "ECv2" {
+			continue
+		}
+		if k.KeyExpiration != "" {
+			if expiration, err := strconv.ParseInt(k.KeyExpiration, 10, 64); err == nil && !time.Now().Before(time.UnixMilli(expiration)) {
+				continue
+			}
+		}
+		key, err := parseGooglePayPublicKey(k.KeyValue)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no valid ECv2 google pay root keys")
+	}
+	return keys, nil
+}
+
+// parseGooglePayPublicKey parses a base64 X.509 SubjectPublicKeyInfo ECDSA key.
+func parseGooglePayPublicKey(value string) (*ecdsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay public key: %w", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google pay public key: %w", err)
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("google pay public key is %T, not ECDSA", pub)
+	}
+	return key, nil
+}
+
+// googlePaySignedBytes concatenates the signed fields, each prefixed with its 4-byte little-endian length.
+func googlePaySignedBytes(parts ...string) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(part)))
+		b = append(b, part...)
+	}
+	return b
+}
+
+// hkdfSHA256 derives length bytes from secret as specified by RFC 5869.
+func hkdfSHA256(secret, salt, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	var out, prev []byte
+	for i := byte(1); len(out) < length; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(prev)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		prev = expand.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
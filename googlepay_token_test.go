@@ -0,0 +1,264 @@
+package alfapay_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+var googlePayTestNow = time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+// googlePayTestKeys are the keys of a test token: Google's root and intermediate signing keys
+// and the merchant's decryption key.
+type googlePayTestKeys struct {
+	root         *ecdsa.PrivateKey
+	intermediate *ecdsa.PrivateKey
+	recipient    *ecdh.PrivateKey
+}
+
+func newGooglePayTestKeys(t *testing.T) *googlePayTestKeys {
+	t.Helper()
+	root, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &googlePayTestKeys{root: root, intermediate: intermediate, recipient: recipient}
+}
+
+func signedBytes(parts ...string) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(part)))
+		b = append(b, part...)
+	}
+	return b
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) string {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func hkdf(secret, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+	var out, prev []byte
+	for i := byte(1); len(out) < length; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(prev)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		prev = expand.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+// token builds the PaymentData of a DIRECT token encrypting message for the recipient.
+func (k *googlePayTestKeys) token(t *testing.T, recipientID string, message alfapay.GooglePayMessage) *alfapay.GooglePayPaymentData {
+	t.Helper()
+	intermediateDER, err := x509.MarshalPKIXPublicKey(&k.intermediate.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedKey := `{"keyValue":"` + base64.StdEncoding.EncodeToString(intermediateDER) +
+		`","keyExpiration":"` + strconv.FormatInt(googlePayTestNow.Add(24*time.Hour).UnixMilli(), 10) + `"}`
+
+	plaintext, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := ephemeral.ECDH(k.recipient.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	keys := hkdf(append(append([]byte(nil), ephemeralPub...), shared...), []byte("Google"), 64)
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(ciphertext, plaintext)
+	mac := hmac.New(sha256.New, keys[32:])
+	mac.Write(ciphertext)
+
+	signedMessage, err := json.Marshal(map[string]string{
+		"encryptedMessage":   base64.StdEncoding.EncodeToString(ciphertext),
+		"ephemeralPublicKey": base64.StdEncoding.EncodeToString(ephemeralPub),
+		"tag":                base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := json.Marshal(alfapay.GooglePayToken{
+		ProtocolVersion: "ECv2",
+		Signature:       sign(t, k.intermediate, signedBytes("Google", recipientID, "ECv2", string(signedMessage))),
+		IntermediateSigningKey: &alfapay.GooglePayIntermediateSigningKey{
+			SignedKey:  signedKey,
+			Signatures: []string{sign(t, k.root, signedBytes("Google", "ECv2", signedKey))},
+		},
+		SignedMessage: string(signedMessage),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := &alfapay.GooglePayPaymentData{APIVersion: 2}
+	data.PaymentMethodData.Type = "CARD"
+	data.PaymentMethodData.Info.CardNetwork = "VISA"
+	data.PaymentMethodData.TokenizationData = alfapay.GooglePayTokenizationData{Type: "DIRECT", Token: string(token)}
+	return data
+}
+
+func (k *googlePayTestKeys) validation() alfapay.GooglePayValidation {
+	return alfapay.GooglePayValidation{
+		Now:               func() time.Time { return googlePayTestNow },
+		GatewayID:         "alfa",
+		RootSigningKeys:   []*ecdsa.PublicKey{&k.root.PublicKey},
+		DecryptionKey:     k.recipient,
+		GatewayMerchantID: "shop-1",
+	}
+}
+
+func googlePayTestMessage(expires time.Time) alfapay.GooglePayMessage {
+	return alfapay.GooglePayMessage{
+		GatewayMerchantID: "shop-1",
+		MessageExpiration: strconv.FormatInt(expires.UnixMilli(), 10),
+		MessageID:         "message-1",
+		PaymentMethod:     "CARD",
+		PaymentMethodDetails: alfapay.GooglePayPaymentMethodDetails{
+			AuthMethod:      alfapay.GooglePayAuthCryptogram3DS,
+			PAN:             "4111111111111111",
+			ExpirationMonth: 12,
+			ExpirationYear:  2030,
+			Cryptogram:      "AgAAAAAAAIR8CQrXcIhbQAAAAAA=",
+		},
+	}
+}
+
+func TestGooglePayTokenValidate(t *testing.T) {
+	keys := newGooglePayTestKeys(t)
+	data := keys.token(t, "gateway:alfa", googlePayTestMessage(googlePayTestNow.Add(time.Hour)))
+
+	if err := data.Validate(keys.validation()); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+
+	token, err := data.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := token.Decrypt(keys.recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.PaymentMethodDetails.PAN != "4111111111111111" || message.PaymentMethodDetails.AuthMethod != alfapay.GooglePayAuthCryptogram3DS {
+		t.Errorf("decrypted message = %+v", message.PaymentMethodDetails)
+	}
+}
+
+func TestGooglePayTokenTamperedSignature(t *testing.T) {
+	keys := newGooglePayTestKeys(t)
+	data := keys.token(t, "gateway:alfa", googlePayTestMessage(googlePayTestNow.Add(time.Hour)))
+
+	token, err := data.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(token.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[len(sig)-1] ^= 0xff
+	token.Signature = base64.StdEncoding.EncodeToString(sig)
+	tampered, err := json.Marshal(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.PaymentMethodData.TokenizationData.Token = string(tampered)
+
+	if err := data.Validate(keys.validation()); !errors.Is(err, alfapay.ErrGooglePaySignature) {
+		t.Errorf("tampered signature: err = %v, want ErrGooglePaySignature", err)
+	}
+}
+
+func TestGooglePayTokenOtherRecipient(t *testing.T) {
+	keys := newGooglePayTestKeys(t)
+	data := keys.token(t, "gateway:other", googlePayTestMessage(googlePayTestNow.Add(time.Hour)))
+
+	if err := data.Validate(keys.validation()); !errors.Is(err, alfapay.ErrGooglePaySignature) {
+		t.Errorf("token for another gateway: err = %v, want ErrGooglePaySignature", err)
+	}
+}
+
+func TestGooglePayTokenExpiredMessage(t *testing.T) {
+	keys := newGooglePayTestKeys(t)
+	data := keys.token(t, "gateway:alfa", googlePayTestMessage(googlePayTestNow.Add(-time.Minute)))
+
+	if err := data.Validate(keys.validation()); !errors.Is(err, alfapay.ErrGooglePayTokenExpired) {
+		t.Errorf("expired message: err = %v, want ErrGooglePayTokenExpired", err)
+	}
+
+	// Without a decryption key the message expiration cannot be read
+	opts := keys.validation()
+	opts.DecryptionKey = nil
+	opts.GatewayMerchantID = ""
+	data.PaymentMethodData.TokenizationData.Type = "PAYMENT_GATEWAY"
+	if err := data.Validate(opts); err != nil {
+		t.Errorf("expired message without a decryption key: err = %v, want signatures only checked", err)
+	}
+}
+
+func TestGooglePayTokenGatewayMerchantMismatch(t *testing.T) {
+	keys := newGooglePayTestKeys(t)
+	message := googlePayTestMessage(googlePayTestNow.Add(time.Hour))
+	message.GatewayMerchantID = "shop-2"
+	data := keys.token(t, "gateway:alfa", message)
+
+	err := data.Validate(keys.validation())
+	if err == nil || errors.Is(err, alfapay.ErrGooglePaySignature) || errors.Is(err, alfapay.ErrGooglePayTokenExpired) {
+		t.Errorf("gateway merchant mismatch: err = %v, want a merchant error", err)
+	}
+
+	opts := keys.validation()
+	opts.DecryptionKey = nil
+	data.PaymentMethodData.TokenizationData.Type = "PAYMENT_GATEWAY"
+	if err := data.Validate(opts); err == nil {
+		t.Error("gateway merchant check without a decryption key succeeded")
+	}
+}
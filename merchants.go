@@ -0,0 +1,292 @@
+package alfapay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownMerchant is returned when no merchant is registered under a key.
+var ErrUnknownMerchant = errors.New("unknown merchant")
+
+// MerchantConfig represents the gateway credentials of a merchant.
+// A sub-merchant sets Parent to use the parent's credentials and MerchantLogin
+// to act on behalf of its own login.
+type MerchantConfig struct {
+	Key           string `json:"key"`
+	UserName      string `json:"userName,omitempty"`
+	Password      string `json:"password,omitempty"`
+	BaseURL       string `json:"baseUrl,omitempty"`        // Default: the registry's client options
+	PaymentPage   string `json:"paymentPageUrl,omitempty"` // See WithPaymentPageURL
+	Parent        string `json:"parent,omitempty"`         // Key of the merchant whose credentials are used
+	MerchantLogin string `json:"merchantLogin,omitempty"`  // Sub-merchant login, see WithMerchantLogin
+}
+
+// merchantsFile is the JSON layout of a merchant configuration file.
+type merchantsFile struct {
+	Merchants []MerchantConfig `json:"merchants"`
+}
+
+// MerchantRegistry holds a client per merchant and routes calls by merchant key.
+// It is safe for concurrent use; the configuration can be replaced while in use.
+type MerchantRegistry struct {
+	opts    []ClientOption
+	optsErr error
+
+	mu        sync.RWMutex
+	merchants map[string]*merchantEntry
+}
+
+type merchantEntry struct {
+	config      MerchantConfig // As configured
+	resolved    MerchantConfig // Sub-merchants carry the parent's credentials
+	client      *Client
+	credentials *merchantCredentials
+}
+
+// merchantCredentials is the CredentialsProvider of a merchant's clients. The registry updates it
+// on reload, so clients already handed out use the new credentials; the replaced credentials are
+// kept as previous, so a request rejected during the rotation is retried with them.
+type merchantCredentials struct {
+	rotation
+}
+
+// Credentials returns the merchant's current credentials.
+func (p *merchantCredentials) Credentials(context.Context) (Credentials, error) {
+	return p.get(), nil
+}
+
+// NewMerchantRegistry creates an empty registry.
+// The options are applied to every client before the merchant's own settings.
+// Clients always use the credentials configured for their merchant, so a
+// WithCredentialsProvider option is rejected by Set and Load.
+func NewMerchantRegistry(opts ...ClientOption) *MerchantRegistry {
+	r := &MerchantRegistry{
+		opts:      opts,
+		merchants: make(map[string]*merchantEntry),
+	}
+	if cred, ok := NewClient("", "", opts...).credentials.(StaticCredentials); !ok || cred != (StaticCredentials{}) {
+		r.optsErr = errors.New("merchant registry options must not set a credentials provider")
+	}
+	return r
+}
+
+// Client returns the client of a merchant.
+func (r *MerchantRegistry) Client(key string) (*Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.merchants[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMerchant, key)
+	}
+	return entry.client, nil
+}
+
+// Keys returns the registered merchant keys in sorted order.
+func (r *MerchantRegistry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.merchants))
+	for key := range r.merchants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Set adds or replaces a merchant. A sub-merchant's parent must already be registered.
+func (r *MerchantRegistry) Set(config MerchantConfig) error {
+	if r.optsErr != nil {
+		return r.optsErr
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	merchants := make(map[string]MerchantConfig, len(r.merchants)+1)
+	for key, entry := range r.merchants {
+		merchants[key] = entry.config
+	}
+	merchants[config.Key] = config
+	resolved, err := resolveMerchants(merchants)
+	if err != nil {
+		return err
+	}
+	r.apply(merchants, resolved)
+	return nil
+}
+
+// Remove removes a merchant. Clients already handed out keep working with its last credentials.
+// A merchant with registered sub-merchants cannot be removed.
+func (r *MerchantRegistry) Remove(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for child, entry := range r.merchants {
+		if entry.config.Parent == key {
+			return fmt.Errorf("merchant %q is the parent of %q", key, child)
+		}
+	}
+	delete(r.merchants, key)
+	return nil
+}
+
+// Load replaces all merchants with the given configuration.
+// Nothing is changed if the configuration is invalid.
+func (r *MerchantRegistry) Load(configs []MerchantConfig) error {
+	if r.optsErr != nil {
+		return r.optsErr
+	}
+	merchants := make(map[string]MerchantConfig, len(configs))
+	for _, config := range configs {
+		if _, ok := merchants[config.Key]; ok {
+			return fmt.Errorf("duplicate merchant %q", config.Key)
+		}
+		merchants[config.Key] = config
+	}
+	resolved, err := resolveMerchants(merchants)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.merchants {
+		if _, ok := resolved[key]; !ok {
+			delete(r.merchants, key)
+		}
+	}
+	r.apply(merchants, resolved)
+	return nil
+}
+
+// LoadFile replaces all merchants with the configuration in a JSON file
+// in the format {"merchants": [{"key": "...", "userName": "...", "password": "..."}, ...]}.
+func (r *MerchantRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read merchant config: %w", err)
+	}
+	var file merchantsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode merchant config: %w", err)
+	}
+	return r.Load(file.Merchants)
+}
+
+// Watch reloads the configuration file whenever its modification time changes,
+// checking every interval until the context is cancelled. Reload errors are passed
+// to onError (if not nil) and the previous configuration stays in effect.
+func (r *MerchantRegistry) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		if err := r.LoadFile(path); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// apply updates merchant credentials and creates clients for merchants whose other settings
+// changed; the others keep their client. The caller must hold the write lock.
+func (r *MerchantRegistry) apply(configs, resolved map[string]MerchantConfig) {
+	for key, config := range resolved {
+		entry, ok := r.merchants[key]
+		if !ok {
+			entry = &merchantEntry{credentials: &merchantCredentials{}}
+			r.merchants[key] = entry
+		}
+		entry.credentials.set(Credentials{UserName: config.UserName, Password: config.Password})
+		if ok && clientSettings(entry.resolved) == clientSettings(config) {
+			entry.config = configs[key]
+			entry.resolved = config
+			continue
+		}
+
+		opts := append([]ClientOption{}, r.opts...)
+		if config.BaseURL != "" {
+			opts = append(opts, WithBaseURL(config.BaseURL))
+		}
+		if config.PaymentPage != "" {
+			opts = append(opts, WithPaymentPageURL(config.PaymentPage))
+		}
+		if config.MerchantLogin != "" {
+			opts = append(opts, WithMerchantLogin(config.MerchantLogin))
+		}
+		opts = append(opts, WithCredentialsProvider(entry.credentials))
+		entry.config = configs[key]
+		entry.resolved = config
+		entry.client = NewClient("", "", opts...)
+	}
+}
+
+// clientSettings returns a merchant config without its credentials, which are not part of the client.
+func clientSettings(config MerchantConfig) MerchantConfig {
+	config.UserName = ""
+	config.Password = ""
+	return config
+}
+
+// resolveMerchants validates merchant configs and copies parent credentials into sub-merchants.
+func resolveMerchants(merchants map[string]MerchantConfig) (map[string]MerchantConfig, error) {
+	resolved := make(map[string]MerchantConfig, len(merchants))
+	for key, config := range merchants {
+		if key == "" {
+			return nil, errors.New("merchant key is required")
+		}
+		if config.Parent != "" {
+			parent, ok := merchants[config.Parent]
+			if !ok {
+				return nil, fmt.Errorf("merchant %q: %w: parent %s", key, ErrUnknownMerchant, config.Parent)
+			}
+			if parent.Parent != "" {
+				return nil, fmt.Errorf("merchant %q: parent %s is itself a sub-merchant", key, config.Parent)
+			}
+			if config.MerchantLogin == "" {
+				return nil, fmt.Errorf("merchant %q: sub-merchant requires merchantLogin", key)
+			}
+			if config.UserName == "" {
+				config.UserName = parent.UserName
+				config.Password = parent.Password
+			}
+			if config.BaseURL == "" {
+				config.BaseURL = parent.BaseURL
+			}
+			if config.PaymentPage == "" {
+				config.PaymentPage = parent.PaymentPage
+			}
+		}
+		if config.UserName == "" || config.Password == "" {
+			return nil, fmt.Errorf("merchant %q: userName and password are required", key)
+		}
+		resolved[key] = config
+	}
+	return resolved, nil
+}
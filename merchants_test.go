@@ -0,0 +1,111 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+func writeMerchantsFile(t *testing.T, path, baseURL, userName string) {
+	t.Helper()
+	config := `{"merchants": [
+		{"key": "shop", "userName": "` + userName + `", "password": "secret", "baseUrl": "` + baseURL + `"},
+		{"key": "shop-kids", "parent": "shop", "merchantLogin": "shop_kids"}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMerchantRegistryReload(t *testing.T) {
+	gateway := &credentialsGateway{accepted: "old-api", reject: "Access denied"}
+	srv := httptest.NewServer(gateway)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "merchants.json")
+	writeMerchantsFile(t, path, srv.URL, "old-api")
+	registry := alfapay.NewMerchantRegistry()
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	shop, err := registry.Client("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kids, err := registry.Client("shop-kids")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeMerchantsFile(t, path, srv.URL, "new-api")
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, _ := registry.Client("shop"); reloaded != shop {
+		t.Error("credential change replaced the client")
+	}
+
+	ctx := context.Background()
+	gateway.accepted = "new-api"
+	for name, client := range map[string]*alfapay.Client{"shop": shop, "shop-kids": kids} {
+		resp, err := client.Status.GetByOrderID(ctx, "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSuccess() {
+			t.Errorf("%s: request after reload failed: %s", name, resp.ErrorMessage)
+		}
+	}
+	if tried := gateway.triedUsers(); len(tried) != 2 || tried[0] != "new-api" || tried[1] != "new-api" {
+		t.Errorf("user names tried = %v, want new-api for both clients", tried)
+	}
+}
+
+func TestMerchantRegistryReloadBeforeGatewayRotation(t *testing.T) {
+	gateway := &credentialsGateway{accepted: "old-api", reject: "Access denied"}
+	srv := httptest.NewServer(gateway)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "merchants.json")
+	writeMerchantsFile(t, path, srv.URL, "old-api")
+	registry := alfapay.NewMerchantRegistry()
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	client, err := registry.Client("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new secret is loaded before the gateway accepts it
+	writeMerchantsFile(t, path, srv.URL, "new-api")
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Status.GetByOrderID(context.Background(), "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSuccess() {
+		t.Fatalf("request failed after reload: %s", resp.ErrorMessage)
+	}
+	if tried := gateway.triedUsers(); len(tried) != 2 || tried[0] != "new-api" || tried[1] != "old-api" {
+		t.Errorf("user names tried = %v, want new-api then old-api", tried)
+	}
+}
+
+func TestMerchantRegistryRejectsCredentialsProvider(t *testing.T) {
+	provider := alfapay.StaticCredentials{UserName: "global-api", Password: "secret"}
+	registry := alfapay.NewMerchantRegistry(alfapay.WithCredentialsProvider(provider))
+	err := registry.Set(alfapay.MerchantConfig{Key: "shop", UserName: "shop-api", Password: "secret"})
+	if err == nil {
+		t.Fatal("registry with a credentials provider option accepted a merchant")
+	}
+	if keys := registry.Keys(); len(keys) != 0 {
+		t.Errorf("keys = %v, want none", keys)
+	}
+}
@@ -0,0 +1,270 @@
+package mockgateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// adminPrefix is the path of the admin API. It is not authenticated; the mock is meant for local use.
+const adminPrefix = "/admin/"
+
+// AdminOrder is an order as returned by the admin API: its gateway status plus mock details.
+type AdminOrder struct {
+	OrderID string `json:"orderId"`
+	*alfapay.GetOrderStatusExtendedResponse
+	ClientID      string       `json:"clientId,omitempty"`
+	MerchantLogin string       `json:"merchantLogin,omitempty"`
+	CallbackURL   string       `json:"callbackUrl,omitempty"`
+	PreAuth       bool         `json:"preAuth,omitempty"`
+	ExpiresAt     *time.Time   `json:"expiresAt,omitempty"`
+	Payout        *AdminPayout `json:"payout,omitempty"`
+}
+
+// AdminPayout is the state of a B2C payout in the admin API.
+type AdminPayout struct {
+	Status        alfapay.PayoutStatus `json:"status"`
+	Description   string               `json:"description,omitempty"`
+	Phone         string               `json:"phone"`
+	BankID        string               `json:"bankId,omitempty"`
+	RecipientName string               `json:"recipientName,omitempty"`
+}
+
+// AdminBinding is a saved card in the admin API.
+type AdminBinding struct {
+	alfapay.Binding
+	Active bool `json:"active"`
+}
+
+// adminAction is the body of POST /admin/orders/{id}/{action}. Fields apply to specific actions.
+type adminAction struct {
+	PAN         string               `json:"pan"`          // pay: card to pay with, default 4111111111111111
+	Status      *alfapay.OrderStatus `json:"status"`       // status: order status to set
+	Operation   string               `json:"operation"`    // callback: operation to report
+	Success     *bool                `json:"success"`      // callback: status parameter, default true
+	Chargeback  *bool                `json:"chargeback"`   // chargeback: flag value, default true
+	Payout      alfapay.PayoutStatus `json:"payoutStatus"` // payout: payout status to set
+	Description string               `json:"description"`  // payout: status description
+}
+
+// handleAdmin serves the admin API:
+//
+//	GET  /admin/orders                     all orders, oldest first
+//	GET  /admin/orders/{id}                an order by ID or number
+//	POST /admin/orders/{id}/pay            pay an unpaid order with {"pan": "..."}, skipping 3-D Secure
+//	POST /admin/orders/{id}/decline        decline an unpaid order
+//	POST /admin/orders/{id}/expire         expire an unpaid order's payment session
+//	POST /admin/orders/{id}/status         force {"status": 0-6}
+//	POST /admin/orders/{id}/chargeback     set the chargeback flag ({"chargeback": false} clears it)
+//	POST /admin/orders/{id}/callback       send {"operation": "...", "success": true} to the callback URL
+//	POST /admin/orders/{id}/payout         set a payout's {"payoutStatus": "...", "description": "..."}
+//	GET  /admin/bindings                   all saved cards
+//	GET  /admin/callbacks                  callback deliveries
+//	POST /admin/reset                      discard all state
+func (g *Gateway) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminPrefix), "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "orders":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"orders": g.AdminOrders()})
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "orders":
+		order, ok := g.AdminOrder(parts[1])
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "orders":
+		var action adminAction
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+		}
+		g.adminOrderAction(w, parts[1], parts[2], action)
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "bindings":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"bindings": g.adminBindings()})
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "callbacks":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"callbacks": g.Callbacks()})
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "reset":
+		g.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAdminError(w, http.StatusNotFound, "unknown admin endpoint")
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// AdminOrders returns all orders, oldest first.
+func (g *Gateway) AdminOrders() []AdminOrder {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	orders := make([]*order, 0, len(g.orders))
+	now := time.Now()
+	for _, o := range g.orders {
+		g.expire(o, now)
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].created.Before(orders[j].created) })
+
+	result := make([]AdminOrder, 0, len(orders))
+	for _, o := range orders {
+		result = append(result, g.adminOrder(o))
+	}
+	return result
+}
+
+// AdminOrder returns an order by ID or number.
+func (g *Gateway) AdminOrder(idOrNumber string) (AdminOrder, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.adminLookup(idOrNumber)
+	if o == nil {
+		return AdminOrder{}, false
+	}
+	return g.adminOrder(o), true
+}
+
+// adminLookup finds an order by ID or number. The caller must hold the lock.
+func (g *Gateway) adminLookup(idOrNumber string) *order {
+	if o := g.lookup(idOrNumber, ""); o != nil {
+		return o
+	}
+	return g.lookup("", idOrNumber)
+}
+
+// adminOrder builds the admin view of an order. The caller must hold the lock.
+func (g *Gateway) adminOrder(o *order) AdminOrder {
+	status := o.response()
+	status.BaseResponse = alfapay.BaseResponse{}
+	status.PaymentAmountInfo.FeeAmount = g.fee(o)
+
+	result := AdminOrder{
+		OrderID:                        o.id,
+		GetOrderStatusExtendedResponse: status,
+		ClientID:                       o.clientID,
+		MerchantLogin:                  o.merchantLogin,
+		CallbackURL:                    o.callbackURL,
+		PreAuth:                        o.preAuth,
+	}
+	if !o.expires.IsZero() {
+		expires := o.expires
+		result.ExpiresAt = &expires
+	}
+	if o.payout != nil {
+		result.Payout = &AdminPayout{
+			Status:        o.payout.status,
+			Description:   o.payout.description,
+			Phone:         o.payout.phone,
+			BankID:        o.payout.bankID,
+			RecipientName: o.payout.recipientName,
+		}
+	}
+	return result
+}
+
+func (g *Gateway) adminBindings() []AdminBinding {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bindings := make([]*binding, 0, len(g.bindings))
+	for _, b := range g.bindings {
+		bindings = append(bindings, b)
+	}
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].created.Before(bindings[j].created) })
+
+	now := time.Now()
+	result := make([]AdminBinding, 0, len(bindings))
+	for _, b := range bindings {
+		result = append(result, AdminBinding{Binding: b.response(now), Active: b.active})
+	}
+	return result
+}
+
+func (g *Gateway) adminOrderAction(w http.ResponseWriter, idOrNumber, name string, action adminAction) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.adminLookup(idOrNumber)
+	if o == nil {
+		writeAdminError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	unpaid := o.status == alfapay.OrderStatusRegistered || o.status == alfapay.OrderStatusACSAuthorization
+
+	switch name {
+	case "pay":
+		if !unpaid {
+			writeAdminError(w, http.StatusConflict, "order is already processed")
+			return
+		}
+		pan := action.PAN
+		if pan == "" {
+			pan = "4111111111111111"
+		}
+		if !luhn(pan) {
+			writeAdminError(w, http.StatusBadRequest, "invalid card number")
+			return
+		}
+		g.pay(o, &card{pan: pan, expiry: "203012", holder: "TEST CARDHOLDER"}, paymentWayCard, false)
+	case "decline":
+		if !unpaid {
+			writeAdminError(w, http.StatusConflict, "order is already processed")
+			return
+		}
+		g.declinePayment(o, o.card, paymentWayCard, actionCodeInsufficientFunds, "Insufficient funds")
+	case "expire":
+		if o.status != alfapay.OrderStatusRegistered {
+			writeAdminError(w, http.StatusConflict, "order is already processed")
+			return
+		}
+		o.expires = time.Now()
+		g.expire(o, o.expires)
+	case "status":
+		if action.Status == nil || *action.Status < alfapay.OrderStatusRegistered || *action.Status > alfapay.OrderStatusDeclined {
+			writeAdminError(w, http.StatusBadRequest, "status must be 0-6")
+			return
+		}
+		o.status = *action.Status
+	case "chargeback":
+		o.chargeback = action.Chargeback == nil || *action.Chargeback
+	case "callback":
+		if o.callbackURL == "" {
+			writeAdminError(w, http.StatusConflict, "order has no callback URL")
+			return
+		}
+		if action.Operation == "" {
+			writeAdminError(w, http.StatusBadRequest, "operation is required")
+			return
+		}
+		g.notify(o, action.Operation, action.Success == nil || *action.Success)
+	case "payout":
+		if o.payout == nil {
+			writeAdminError(w, http.StatusConflict, "order is not a payout")
+			return
+		}
+		switch action.Payout {
+		case alfapay.PayoutStatusCreated, alfapay.PayoutStatusInProgress, alfapay.PayoutStatusSuccess,
+			alfapay.PayoutStatusDeclined, alfapay.PayoutStatusError:
+		default:
+			writeAdminError(w, http.StatusBadRequest, "unknown payoutStatus")
+			return
+		}
+		g.setPayoutStatus(o, action.Payout, action.Description)
+		o.payout.held = true
+	default:
+		writeAdminError(w, http.StatusNotFound, "unknown action "+name)
+		return
+	}
+	writeJSON(w, http.StatusOK, g.adminOrder(o))
+}
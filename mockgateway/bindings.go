@@ -0,0 +1,209 @@
+package mockgateway
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// binding is a saved card.
+type binding struct {
+	id       string
+	clientID string
+	pan      string
+	expiry   string // YYYYMM
+	holder   string
+	active   bool
+	created  time.Time
+	lastUsed time.Time
+}
+
+// card returns the saved card for a new payment.
+func (b *binding) card() *card {
+	return &card{pan: b.pan, expiry: b.expiry, holder: b.holder}
+}
+
+func (b *binding) expired(now time.Time) bool {
+	expires, err := alfapay.ParseExpiry(b.expiry)
+	return err == nil && !now.Before(expires)
+}
+
+func (b *binding) response(now time.Time) alfapay.Binding {
+	return alfapay.Binding{
+		BindingID:      b.id,
+		MaskedPan:      maskPAN(b.pan),
+		ExpiryDate:     b.expiry,
+		ClientID:       b.clientID,
+		IsExpired:      b.expired(now),
+		CardholderName: b.holder,
+		PaymentSystem:  paymentSystem(b.pan),
+		CreatedDate:    millis(b.created),
+		LastUsedDate:   millis(b.lastUsed),
+	}
+}
+
+func paymentSystem(pan string) string {
+	switch {
+	case strings.HasPrefix(pan, "4"):
+		return "VISA"
+	case strings.HasPrefix(pan, "5"):
+		return "MASTERCARD"
+	case strings.HasPrefix(pan, "2"):
+		return "MIR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// saveCard creates a binding for the card an order was paid with, or returns the client's existing one.
+// The caller must hold the lock.
+func (g *Gateway) saveCard(o *order) {
+	if o.clientID == "" || o.card == nil {
+		return
+	}
+	for _, b := range g.bindings {
+		if b.clientID == o.clientID && b.pan == o.card.pan {
+			b.active = true
+			b.expiry = o.card.expiry
+			o.bindingID = b.id
+			return
+		}
+	}
+	b := &binding{
+		id:       newID(),
+		clientID: o.clientID,
+		pan:      o.card.pan,
+		expiry:   o.card.expiry,
+		holder:   o.card.holder,
+		active:   true,
+		created:  time.Now(),
+	}
+	g.bindings[b.id] = b
+	o.bindingID = b.id
+}
+
+func (g *Gateway) handleGetBindings(w http.ResponseWriter, r *http.Request) {
+	g.listBindings(w, r, false)
+}
+
+func (g *Gateway) handleGetAllBindings(w http.ResponseWriter, r *http.Request) {
+	g.listBindings(w, r, true)
+}
+
+func (g *Gateway) listBindings(w http.ResponseWriter, r *http.Request, inactive bool) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	clientID := params.Get("clientId")
+	if clientID == "" {
+		writeError(w, ErrorCodeInvalidParam, "clientId is required")
+		return
+	}
+	showExpired := params.Get("showExpired") == "true"
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.writeBindings(w, func(b *binding) bool {
+		return b.clientID == clientID && (b.active || inactive) && (showExpired || !b.expired(now))
+	})
+}
+
+func (g *Gateway) handleGetBindingsByCardOrID(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	bindingID, pan := params.Get("bindingId"), params.Get("pan")
+	if bindingID == "" && pan == "" {
+		writeError(w, ErrorCodeInvalidParam, "bindingId or pan is required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.writeBindings(w, func(b *binding) bool {
+		return (bindingID != "" && b.id == bindingID) || (pan != "" && b.pan == pan)
+	})
+}
+
+// writeBindings writes the bindings matching a filter, sorted by creation. The caller must hold the lock.
+func (g *Gateway) writeBindings(w http.ResponseWriter, match func(*binding) bool) {
+	var matched []*binding
+	for _, b := range g.bindings {
+		if match(b) {
+			matched = append(matched, b)
+		}
+	}
+	if len(matched) == 0 {
+		writeError(w, ErrorCodeNotFound, "Information not found")
+		return
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].created.Before(matched[j].created) })
+
+	now := time.Now()
+	resp := alfapay.GetBindingsResponse{BaseResponse: alfapay.BaseResponse{ErrorCode: "0"}}
+	for _, b := range matched {
+		resp.Bindings = append(resp.Bindings, b.response(now))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (g *Gateway) handleBindCard(w http.ResponseWriter, r *http.Request) {
+	g.setBindingActive(w, r, true)
+}
+
+func (g *Gateway) handleUnbindCard(w http.ResponseWriter, r *http.Request) {
+	g.setBindingActive(w, r, false)
+}
+
+func (g *Gateway) setBindingActive(w http.ResponseWriter, r *http.Request, active bool) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := g.bindings[params.Get("bindingId")]
+	if b == nil {
+		writeError(w, ErrorCodeNotFound, "Binding not found")
+		return
+	}
+	if b.active == active {
+		writeError(w, ErrorCodeInvalidState, "Binding is already in the requested state")
+		return
+	}
+	b.active = active
+	writeOK(w)
+}
+
+func (g *Gateway) handleExtendBinding(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	newExpiry := params.Get("newExpiry")
+	if _, err := alfapay.ParseExpiry(newExpiry); err != nil {
+		writeError(w, ErrorCodeInvalidParam, "newExpiry must be in the format YYYYMM")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := g.bindings[params.Get("bindingId")]
+	if b == nil {
+		writeError(w, ErrorCodeNotFound, "Binding not found")
+		return
+	}
+	b.expiry = newExpiry
+	writeOK(w)
+}
@@ -0,0 +1,109 @@
+package mockgateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Callback operations, as sent by the gateway in the operation parameter.
+const (
+	operationApproved          = "approved"
+	operationDeposited         = "deposited"
+	operationReversed          = "reversed"
+	operationRefunded          = "refunded"
+	operationDeclinedByTimeout = "declinedByTimeout"
+)
+
+// CallbackDelivery records a callback sent to an order's DynamicCallbackURL.
+type CallbackDelivery struct {
+	OrderID    string     `json:"orderId"`
+	URL        string     `json:"url"`
+	Params     url.Values `json:"params"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	SentAt     time.Time  `json:"sentAt"`
+}
+
+// Callbacks returns the callback deliveries made so far, oldest first.
+func (g *Gateway) Callbacks() []CallbackDelivery {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]CallbackDelivery(nil), g.callbacks...)
+}
+
+// notify sends a callback for an order operation in the background if the order has a callback URL.
+// The caller must hold the lock.
+func (g *Gateway) notify(o *order, operation string, success bool) {
+	if o.callbackURL == "" {
+		return
+	}
+
+	params := url.Values{}
+	params.Set("mdOrder", o.id)
+	params.Set("orderNumber", o.number)
+	params.Set("operation", operation)
+	params.Set("status", "0")
+	if success {
+		params.Set("status", "1")
+	}
+	if g.opts.CallbackSecret != "" {
+		params.Set("checksum", Checksum(params, g.opts.CallbackSecret))
+	}
+
+	g.pending.Add(1)
+	go g.deliver(CallbackDelivery{OrderID: o.id, URL: o.callbackURL, Params: params})
+}
+
+// deliver sends a callback and records the outcome.
+func (g *Gateway) deliver(delivery CallbackDelivery) {
+	defer g.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	delivery.SentAt = time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, withQuery(delivery.URL, delivery.Params), nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = g.opts.CallbackClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			delivery.StatusCode = resp.StatusCode
+		}
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	g.logf("callback %s %s: status %d %s", delivery.Params.Get("operation"), delivery.URL, delivery.StatusCode, delivery.Error)
+
+	g.mu.Lock()
+	g.callbacks = append(g.callbacks, delivery)
+	g.mu.Unlock()
+}
+
+// Checksum computes the callback checksum: HMAC-SHA256 of "name;value;" pairs sorted by name,
+// excluding checksum and sign_alias, in upper-case hex.
+func Checksum(params url.Values, secret string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if name != "checksum" && name != "sign_alias" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ";" + params.Get(name) + ";")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(b.String()))
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,125 @@
+package mockgateway_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+func Example() {
+	gateway := mockgateway.New(mockgateway.Options{UserName: "test-api", Password: "test"})
+	srv := httptest.NewServer(gateway)
+	defer srv.Close()
+
+	client := alfapay.NewClient("test-api", "test", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-1",
+		Amount:      150000,
+		ReturnURL:   "https://shop.example.com/return",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Pay the order as a customer would on the payment page at order.FormURL
+	resp, err := http.Post(srv.URL+"/admin/orders/"+order.OrderID+"/pay", "application/json",
+		strings.NewReader(`{"pan": "4111111111111111"}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: order.OrderID, Amount: 50000}); err != nil {
+		log.Fatal(err)
+	}
+
+	status, err := client.Status.GetExtended(ctx, &alfapay.GetOrderStatusRequest{OrderID: order.OrderID})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Status:", status.OrderStatus)
+	fmt.Println("Card:", status.CardAuthInfo.MaskedPan)
+	fmt.Println("Refunded:", status.PaymentAmountInfo.RefundedAmount)
+	// Output:
+	// Status: 2
+	// Card: 411111**1111
+	// Refunded: 50000
+}
+
+func Example_declinedCard() {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-2",
+		Amount:      1000,
+		ReturnURL:   "https://shop.example.com/return",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 4012888888881881 is one of the cards declined with insufficient funds
+	resp, err := http.Post(srv.URL+"/admin/orders/ORDER-2/pay", "application/json",
+		strings.NewReader(`{"pan": "4012888888881881"}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+
+	status, err := client.Status.GetExtended(ctx, &alfapay.GetOrderStatusRequest{OrderID: order.OrderID})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Status:", status.OrderStatus)
+	fmt.Println("Action code:", status.ActionCode, status.ActionCodeDescription)
+	// Output:
+	// Status: 6
+	// Action code: 116 Insufficient funds
+}
+
+func Example_lastOrders() {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("test-api", "test", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	for _, number := range []string{"ORDER-1", "ORDER-2", "ORDER-3"} {
+		_, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+			OrderNumber: number, Amount: 10000, ReturnURL: "https://shop.example.com/return",
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// The range has second resolution, so orders registered within the last second are included
+	err := client.Status.EachLastOrder(ctx, &alfapay.GetLastOrdersRequest{
+		Size:     2,
+		FromTime: time.Now().Add(-time.Minute),
+		ToTime:   time.Now(),
+	}, func(order *alfapay.GetOrderStatusExtendedResponse) error {
+		fmt.Println(order.OrderNumber)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// ORDER-1
+	// ORDER-2
+	// ORDER-3
+}
@@ -0,0 +1,296 @@
+// Package mockgateway is a local emulator of the Alfa Payments gateway for end-to-end tests.
+//
+// The Gateway implements the REST endpoints used by the alfapay client, serves a hosted
+// payment page at the FormURL of registered orders, sends callbacks to DynamicCallbackURL
+// and exposes an admin API under /admin/ to inspect and manipulate orders.
+// All state is kept in memory. The cmd/alfapay-mock binary serves it over HTTP.
+//
+// Gateway endpoints live under PathPrefix, so clients point at it with
+// alfapay.WithBaseURL("http://<host>" + mockgateway.PathPrefix).
+package mockgateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// PathPrefix is the path under which the gateway endpoints are served, as on the real gateway.
+const PathPrefix = "/payment"
+
+// DefaultSessionTimeout is how long a registered order can be paid unless the request sets
+// sessionTimeoutSecs or expirationDate.
+const DefaultSessionTimeout = 20 * time.Minute
+
+// Error codes returned by the mock, following the gateway.
+const (
+	ErrorCodeDuplicate    = "1" // Order number already processed
+	ErrorCodeNotFound     = "2" // Bindings or recipient not found
+	ErrorCodeInvalidParam = "5" // Access denied or invalid parameter
+	ErrorCodeUnknownOrder = "6" // Order not found
+	ErrorCodeInvalidState = "7" // Operation not allowed in the order's state
+)
+
+// Options configures a Gateway.
+type Options struct {
+	// UserName and Password are the accepted API credentials. Any credentials are accepted if UserName is empty.
+	UserName string
+	Password string
+
+	// PublicURL is the externally visible gateway URL used in FormURL, acsUrl and qrUrl,
+	// e.g. http://localhost:8080/payment. Default: derived from the request host.
+	PublicURL string
+
+	// CallbackSecret signs callbacks with the checksum parameter (HMAC-SHA256) if set.
+	CallbackSecret string
+	// CallbackClient sends callbacks. Default: a client with a 10 second timeout.
+	CallbackClient *http.Client
+
+	// Cards maps test card numbers to payment scenarios. Default: DefaultCards.
+	// Cards not in the map are approved.
+	Cards map[string]Scenario
+
+	// FeeRate is the acquiring fee reported in PaymentAmountInfo.FeeAmount, as a fraction of the deposited amount.
+	FeeRate float64
+
+	// Logf logs requests and callback deliveries if set, e.g. log.Printf.
+	Logf func(format string, args ...interface{})
+}
+
+// Gateway is an in-memory emulation of the Alfa Payments gateway. It implements http.Handler.
+type Gateway struct {
+	opts   Options
+	routes map[string]http.HandlerFunc
+	banks  *alfapay.SBPBankDirectory
+
+	mu            sync.Mutex
+	orders        map[string]*order // By order ID
+	numbers       map[string]*order // By order number
+	bindings      map[string]*binding
+	sbpBindings   map[string]*sbpBinding
+	subscriptions map[string]*subscription
+	callbacks     []CallbackDelivery
+
+	pending sync.WaitGroup // Callbacks being delivered
+}
+
+// New creates a gateway with empty state.
+func New(opts Options) *Gateway {
+	if opts.CallbackClient == nil {
+		opts.CallbackClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Cards == nil {
+		opts.Cards = DefaultCards
+	}
+
+	g := &Gateway{
+		opts:  opts,
+		banks: alfapay.DefaultSBPBankDirectory(),
+	}
+	g.reset()
+
+	g.routes = map[string]http.HandlerFunc{
+		"/rest/register.do":                       g.handleRegister,
+		"/rest/registerPreAuth.do":                g.handleRegisterPreAuth,
+		"/rest/getOrderStatusExtended.do":         g.handleStatus,
+		"/rest/getLastOrdersForMerchants.do":      g.handleLastOrders,
+		"/rest/decline.do":                        g.handleDecline,
+		"/rest/addParams.do":                      g.handleAddParams,
+		"/rest/deposit.do":                        g.handleDeposit,
+		"/rest/reverse.do":                        g.handleReverse,
+		"/rest/refund.do":                         g.handleRefund,
+		"/rest/instantRefund.do":                  g.handleRefund,
+		"/rest/paymentOrderBinding.do":            g.handlePaymentOrderBinding,
+		"/rest/instantPayment.do":                 g.handleInstantPayment,
+		"/rest/finish3dsPayment.do":               g.handleFinish3DS,
+		"/rest/verifyEnrollment.do":               g.handleVerifyEnrollment,
+		"/recurrentPayment.do":                    g.handleRecurrentPayment,
+		"/rest/getBindings.do":                    g.handleGetBindings,
+		"/rest/getAllBindings.do":                 g.handleGetAllBindings,
+		"/rest/getBindingsByCardOrId.do":          g.handleGetBindingsByCardOrID,
+		"/rest/bindCard.do":                       g.handleBindCard,
+		"/rest/unBindCard.do":                     g.handleUnbindCard,
+		"/rest/extendBinding.do":                  g.handleExtendBinding,
+		"/rest/sbp/c2b/qr/dynamic/get.do":         g.handleSBPGetQR,
+		"/rest/sbp/c2b/qr/status.do":              g.handleSBPQRStatus,
+		"/rest/sbp/c2b/qr/dynamic/reject.do":      g.handleSBPRejectQR,
+		"/rest/sbp/c2b/bind.do":                   g.handleSBPBind,
+		"/rest/sbp/c2b/unBind.do":                 g.handleSBPUnbind,
+		"/rest/sbp/c2b/getBindings.do":            g.handleSBPGetBindings,
+		"/rest/sbp/c2b/qr/subscription/get.do":    g.handleSBPSubscriptionQR,
+		"/rest/sbp/c2b/qr/subscription/status.do": g.handleSBPSubscriptionStatus,
+		"/rest/sbp/c2b/recurrentPayment.do":       g.handleSBPRecurrent,
+		"/rest/sbp/getMembers.do":                 g.handleSBPMembers,
+		"/rest/sbp/b2b/getPayload.do":             g.handleB2BPayload,
+		"/rest/sbp/b2b/perform.do":                g.handleB2BPerform,
+		"/rest/sbp/b2c/performPayout.do":          g.handleB2CPerformPayout,
+		"/rest/sbp/b2c/checkPayout.do":            g.handleB2CCheckPayout,
+		"/rest/sbp/b2c/getPayoutStatus.do":        g.handleB2CPayoutStatus,
+		"/applepay/payment.do":                    g.walletHandler(walletApplePay),
+		"/google/payment.do":                      g.walletHandler(walletGooglePay),
+		"/samsung/payment.do":                     g.walletHandler(walletSamsungPay),
+		"/samsung/paymentDirect.do":               g.walletHandler(walletSamsungPay),
+		"/mir/payment.do":                         g.walletHandler(walletMirPay),
+		"/mir/paymentDirect.do":                   g.walletHandler(walletMirPayDirect),
+		"/yandex/payment.do":                      g.walletHandler(walletYandexPay),
+		"/yandex/paymentDirect.do":                g.walletHandler(walletYandexPay),
+		"/yandex/instantPayment.do":               g.walletHandler(walletYandexPay),
+		pagePath:                                  g.handlePaymentPage,
+		acsPath:                                   g.handleACSPage,
+		sbpPayPath:                                g.handleSBPPayPage,
+		sbpSubscribePath:                          g.handleSBPSubscribePage,
+	}
+	return g
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.logf("%s %s", r.Method, r.URL.Path)
+
+	if strings.HasPrefix(r.URL.Path, adminPrefix) {
+		g.handleAdmin(w, r)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if path == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if strings.HasPrefix(path, "/merchants/") && strings.HasSuffix(path, ".html") {
+		path = pagePath
+	}
+
+	handler, ok := g.routes[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+// Wait blocks until callbacks being delivered have completed.
+func (g *Gateway) Wait() {
+	g.pending.Wait()
+}
+
+// Reset discards all orders, bindings and callback deliveries.
+func (g *Gateway) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reset()
+}
+
+func (g *Gateway) reset() {
+	g.orders = make(map[string]*order)
+	g.numbers = make(map[string]*order)
+	g.bindings = make(map[string]*binding)
+	g.sbpBindings = make(map[string]*sbpBinding)
+	g.subscriptions = make(map[string]*subscription)
+	g.callbacks = nil
+}
+
+func (g *Gateway) logf(format string, args ...interface{}) {
+	if g.opts.Logf != nil {
+		g.opts.Logf(format, args...)
+	}
+}
+
+// publicURL returns the gateway URL as seen by browsers.
+func (g *Gateway) publicURL(r *http.Request) string {
+	if g.opts.PublicURL != "" {
+		return strings.TrimRight(g.opts.PublicURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + PathPrefix
+}
+
+// formParams parses query and form parameters and checks the credentials.
+// It writes an error response and returns false if the request is rejected.
+func (g *Gateway) formParams(w http.ResponseWriter, r *http.Request) (url.Values, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, ErrorCodeInvalidParam, "Invalid request parameters")
+		return nil, false
+	}
+	if !g.authorized(r.Form.Get("userName"), r.Form.Get("password")) {
+		writeError(w, ErrorCodeInvalidParam, "Access denied")
+		return nil, false
+	}
+	return r.Form, true
+}
+
+// jsonBody decodes a JSON request body and checks the credentials passed in the query.
+func (g *Gateway) jsonBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	query := r.URL.Query()
+	if !g.authorized(query.Get("userName"), query.Get("password")) {
+		writeError(w, ErrorCodeInvalidParam, "Access denied")
+		return false
+	}
+	return decodeBody(w, r, v)
+}
+
+func (g *Gateway) authorized(userName, password string) bool {
+	return g.opts.UserName == "" || (userName == g.opts.UserName && password == g.opts.Password)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	data, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		writeError(w, ErrorCodeInvalidParam, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	writeJSON(w, http.StatusOK, alfapay.BaseResponse{ErrorCode: alfapay.FlexString(code), ErrorMessage: message})
+}
+
+func writeOK(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, alfapay.BaseResponse{ErrorCode: "0", ErrorMessage: "Success"})
+}
+
+// newID returns a random identifier in UUID format, like gateway order IDs.
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("mockgateway: failed to generate id: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// withQuery appends parameters to a URL.
+func withQuery(rawURL string, params url.Values) string {
+	if rawURL == "" {
+		return ""
+	}
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + params.Encode()
+}
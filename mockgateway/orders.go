@@ -0,0 +1,858 @@
+package mockgateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// Scenario is the outcome of a payment with a test card.
+type Scenario string
+
+const (
+	ScenarioSuccess   Scenario = "success"   // Approved without 3-D Secure
+	ScenarioChallenge Scenario = "challenge" // 3-D Secure challenge page, approved if confirmed
+	ScenarioDecline   Scenario = "decline"   // Declined for insufficient funds
+)
+
+// DefaultCards are the test cards shown on the payment page. Any expiry date in the future and any CVC are accepted.
+var DefaultCards = map[string]Scenario{
+	"4111111111111111": ScenarioSuccess,
+	"5555555555555599": ScenarioSuccess,
+	"2200000000000053": ScenarioSuccess,
+	"4242424242424242": ScenarioChallenge,
+	"5555555555554444": ScenarioChallenge,
+	"4012888888881881": ScenarioDecline,
+	"5105105105105100": ScenarioDecline,
+	"5000000000000009": ScenarioDecline,
+}
+
+// Action codes reported for declined payments.
+const (
+	actionCodeInsufficientFunds = 116
+	actionCodeSessionExpired    = -2007
+	actionCode3DSFailed         = 151017
+)
+
+// Payment ways reported in order statuses.
+const (
+	paymentWayCard         = "CARD"
+	paymentWayBinding      = "CARD_BINDING"
+	paymentWaySBP          = "SBP_C2B"
+	paymentWaySBPBinding   = "SBP_C2B_BINDING"
+	paymentWaySBPB2B       = "SBP_B2B"
+	paymentWaySBPB2CPayout = "SBP_B2C"
+)
+
+// order is the state of a gateway order.
+type order struct {
+	id, number    string
+	amount        int64
+	currency      string
+	description   string
+	returnURL     string
+	failURL       string
+	callbackURL   string
+	clientID      string
+	merchantLogin string
+	email, phone  string
+	ip            string
+	preAuth       bool
+	params        []alfapay.OrderAddendum
+
+	status                alfapay.OrderStatus
+	actionCode            int
+	actionCodeDescription string
+	paymentWay            string
+	chargeback            bool
+
+	created, expires time.Time
+	authorized       time.Time
+	deposited        time.Time
+	refundedAt       time.Time
+	reversed         time.Time
+
+	approvedAmount  int64
+	depositedAmount int64
+	refundedAmount  int64
+	refunds         []alfapay.Refund
+
+	card      *card
+	saveCard  bool // Payer asked to save the card, pending 3-D Secure
+	bindingID string
+	payout    *payout
+}
+
+// card is the card an order was paid with.
+type card struct {
+	pan          string
+	expiry       string // YYYYMM
+	holder       string
+	approvalCode string
+}
+
+func (c *card) masked() string {
+	return maskPAN(c.pan)
+}
+
+func maskPAN(pan string) string {
+	if len(pan) < 10 {
+		return pan
+	}
+	return pan[:6] + "**" + pan[len(pan)-4:]
+}
+
+func (o *order) response() *alfapay.GetOrderStatusExtendedResponse {
+	resp := &alfapay.GetOrderStatusExtendedResponse{
+		BaseResponse:          alfapay.BaseResponse{ErrorCode: "0", ErrorMessage: "Success"},
+		OrderNumber:           o.number,
+		OrderStatus:           o.status,
+		ActionCode:            o.actionCode,
+		ActionCodeDescription: o.actionCodeDescription,
+		Amount:                o.amount,
+		Currency:              o.currency,
+		Date:                  millis(o.created),
+		OrderDescription:      o.description,
+		IP:                    o.ip,
+		AuthDateTime:          millis(o.authorized),
+		DepositedDate:         millis(o.deposited),
+		RefundedDate:          millis(o.refundedAt),
+		ReversedDate:          millis(o.reversed),
+		PaymentWay:            o.paymentWay,
+		Chargeback:            o.chargeback,
+		Refunds:               append([]alfapay.Refund(nil), o.refunds...),
+		MerchantOrderParams:   append([]alfapay.OrderAddendum(nil), o.params...),
+		Attributes:            []alfapay.OrderAddendum{{Name: "mdOrder", Value: o.id}},
+		PaymentAmountInfo: &alfapay.PaymentAmountInfo{
+			ApprovedAmount:  o.approvedAmount,
+			DepositedAmount: o.depositedAmount,
+			RefundedAmount:  o.refundedAmount,
+			PaymentState:    paymentState(o.status),
+		},
+	}
+	if o.email != "" || o.phone != "" {
+		resp.PayerData = &alfapay.PayerData{Email: o.email, Phone: o.phone}
+	}
+	if o.card != nil {
+		resp.CardAuthInfo = &alfapay.CardAuthInfo{
+			MaskedPan:      o.card.masked(),
+			Expiration:     o.card.expiry,
+			CardholderName: o.card.holder,
+			ApprovalCode:   o.card.approvalCode,
+		}
+		resp.BankInfo = &alfapay.BankInfo{BankName: "MOCK BANK", BankCountryCode: "RU", BankCountryName: "Россия"}
+		resp.TerminalID = "12345678"
+	}
+	if o.bindingID != "" {
+		resp.BindingInfo = &alfapay.CardBindingInfo{BindingID: o.bindingID, ClientID: o.clientID}
+	}
+	return resp
+}
+
+// fee returns the acquiring fee of the deposited amount.
+func (g *Gateway) fee(o *order) int64 {
+	return int64(math.Round(float64(o.depositedAmount) * g.opts.FeeRate))
+}
+
+// paymentState returns the state name used by getLastOrdersForMerchants and PaymentAmountInfo.
+func paymentState(status alfapay.OrderStatus) string {
+	switch status {
+	case alfapay.OrderStatusPreAuthorized:
+		return "APPROVED"
+	case alfapay.OrderStatusFullyAuthorized:
+		return "DEPOSITED"
+	case alfapay.OrderStatusCancelled:
+		return "REVERSED"
+	case alfapay.OrderStatusRefunded:
+		return "REFUNDED"
+	case alfapay.OrderStatusDeclined:
+		return "DECLINED"
+	default:
+		return "CREATED"
+	}
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// lookup finds an order by ID or number and expires it if its payment session is over.
+// The caller must hold the lock.
+func (g *Gateway) lookup(orderID, orderNumber string) *order {
+	o := g.orders[orderID]
+	if o == nil && orderID == "" {
+		o = g.numbers[orderNumber]
+	}
+	if o != nil {
+		g.expire(o, time.Now())
+	}
+	return o
+}
+
+// expire declines an unpaid order whose payment session is over. The caller must hold the lock.
+func (g *Gateway) expire(o *order, now time.Time) {
+	if o.status != alfapay.OrderStatusRegistered || o.expires.IsZero() || now.Before(o.expires) {
+		return
+	}
+	o.status = alfapay.OrderStatusDeclined
+	o.actionCode = actionCodeSessionExpired
+	o.actionCodeDescription = "Payment session expired"
+	g.notify(o, operationDeclinedByTimeout, true)
+}
+
+// newOrder registers an order. The caller must hold the lock.
+func (g *Gateway) newOrder(number string, amount int64) *order {
+	o := &order{
+		id:       newID(),
+		number:   number,
+		amount:   amount,
+		currency: "643",
+		created:  time.Now(),
+	}
+	g.orders[o.id] = o
+	if number != "" {
+		g.numbers[number] = o
+	}
+	return o
+}
+
+// authorize completes a payment: the amount is held for pre-authorized orders and deposited otherwise.
+// The caller must hold the lock.
+func (g *Gateway) authorize(o *order, c *card, paymentWay string) {
+	now := time.Now()
+	o.card = c
+	if c != nil && c.approvalCode == "" {
+		c.approvalCode = strconv.Itoa(100000 + int(now.UnixNano()%900000))
+	}
+	o.paymentWay = paymentWay
+	o.authorized = now
+	o.approvedAmount = o.amount
+	o.actionCode = 0
+	o.actionCodeDescription = ""
+
+	if o.preAuth {
+		o.status = alfapay.OrderStatusPreAuthorized
+		g.notify(o, operationApproved, true)
+		return
+	}
+	o.status = alfapay.OrderStatusFullyAuthorized
+	o.deposited = now
+	o.depositedAmount = o.amount
+	g.notify(o, operationDeposited, true)
+}
+
+// declinePayment records a failed payment attempt. The caller must hold the lock.
+func (g *Gateway) declinePayment(o *order, c *card, paymentWay string, actionCode int, description string) {
+	o.card = c
+	o.paymentWay = paymentWay
+	o.status = alfapay.OrderStatusDeclined
+	o.actionCode = actionCode
+	o.actionCodeDescription = description
+	g.notify(o, operationApproved, false)
+}
+
+// pay runs the scenario of a card against an order. It returns true if a 3-D Secure challenge is required.
+// The caller must hold the lock.
+func (g *Gateway) pay(o *order, c *card, paymentWay string, challenge bool) bool {
+	switch g.scenario(c.pan) {
+	case ScenarioDecline:
+		g.declinePayment(o, c, paymentWay, actionCodeInsufficientFunds, "Insufficient funds")
+	case ScenarioChallenge:
+		if challenge {
+			o.card = c
+			o.paymentWay = paymentWay
+			o.status = alfapay.OrderStatusACSAuthorization
+			return true
+		}
+		g.authorize(o, c, paymentWay)
+	default:
+		g.authorize(o, c, paymentWay)
+	}
+	return false
+}
+
+// finishChallenge completes a payment waiting for 3-D Secure. The caller must hold the lock.
+func (g *Gateway) finishChallenge(o *order, confirmed bool) {
+	if confirmed {
+		g.authorize(o, o.card, o.paymentWay)
+		return
+	}
+	g.declinePayment(o, o.card, o.paymentWay, actionCode3DSFailed, "3-D Secure authentication failed")
+}
+
+func (g *Gateway) scenario(pan string) Scenario {
+	if s, ok := g.opts.Cards[pan]; ok {
+		return s
+	}
+	return ScenarioSuccess
+}
+
+// refund returns part of the deposited amount. The caller must hold the lock.
+func (g *Gateway) refund(o *order, amount int64) (alfapay.Refund, string, string) {
+	if o.status != alfapay.OrderStatusFullyAuthorized {
+		return alfapay.Refund{}, ErrorCodeInvalidState, "Order must be deposited to be refunded"
+	}
+	if amount <= 0 || amount > o.depositedAmount-o.refundedAmount {
+		return alfapay.Refund{}, ErrorCodeInvalidState, "Refund amount exceeds the deposited amount"
+	}
+
+	now := time.Now()
+	refund := alfapay.Refund{RefundID: newID(), RefundDate: now.UnixMilli(), RefundAmount: amount}
+	o.refunds = append(o.refunds, refund)
+	o.refundedAmount += amount
+	o.refundedAt = now
+	if o.refundedAmount == o.depositedAmount {
+		o.status = alfapay.OrderStatusRefunded
+	}
+	g.notify(o, operationRefunded, true)
+	return refund, "", ""
+}
+
+func (g *Gateway) handleRegister(w http.ResponseWriter, r *http.Request) {
+	g.register(w, r, false)
+}
+
+func (g *Gateway) handleRegisterPreAuth(w http.ResponseWriter, r *http.Request) {
+	g.register(w, r, true)
+}
+
+func (g *Gateway) register(w http.ResponseWriter, r *http.Request, preAuth bool) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	number := params.Get("orderNumber")
+	amount, err := strconv.ParseInt(params.Get("amount"), 10, 64)
+	if number == "" || err != nil || amount <= 0 {
+		writeError(w, ErrorCodeInvalidParam, "orderNumber and a positive amount are required")
+		return
+	}
+	if params.Get("returnUrl") == "" {
+		writeError(w, ErrorCodeInvalidParam, "returnUrl is required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.numbers[number]; exists {
+		writeError(w, ErrorCodeDuplicate, "Order with this number was already processed")
+		return
+	}
+
+	o := g.newOrder(number, amount)
+	o.preAuth = preAuth
+	o.returnURL = params.Get("returnUrl")
+	o.failURL = params.Get("failUrl")
+	o.description = params.Get("description")
+	o.clientID = params.Get("clientId")
+	o.merchantLogin = params.Get("merchantLogin")
+	o.email = params.Get("email")
+	o.phone = params.Get("phone")
+	o.callbackURL = params.Get("dynamicCallbackUrl")
+	if currency := params.Get("currency"); currency != "" {
+		o.currency = currency
+	}
+
+	o.expires = o.created.Add(DefaultSessionTimeout)
+	if secs, err := strconv.Atoi(params.Get("sessionTimeoutSecs")); err == nil && secs > 0 {
+		o.expires = o.created.Add(time.Duration(secs) * time.Second)
+	}
+	if t, err := alfapay.ParseDateTime(params.Get("expirationDate")); err == nil && !t.IsZero() {
+		o.expires = t
+	}
+
+	writeJSON(w, http.StatusOK, alfapay.RegisterOrderResponse{
+		OrderID: o.id,
+		FormURL: g.formURL(r, o),
+	})
+}
+
+func (g *Gateway) handleStatus(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), params.Get("orderNumber"))
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	resp := o.response()
+	resp.PaymentAmountInfo.FeeAmount = g.fee(o)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (g *Gateway) handleLastOrders(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	from, errFrom := time.ParseInLocation(alfapay.LastOrdersDateLayout, params.Get("from"), alfapay.MoscowTime)
+	to, errTo := time.ParseInLocation(alfapay.LastOrdersDateLayout, params.Get("to"), alfapay.MoscowTime)
+	if errFrom != nil || errTo != nil {
+		writeError(w, ErrorCodeInvalidParam, "from and to are required in the format yyyyMMddHHmmss")
+		return
+	}
+	to = to.Add(time.Second - 1) // The range has second resolution and includes the whole last second
+	page, _ := strconv.Atoi(params.Get("page"))
+	size, _ := strconv.Atoi(params.Get("size"))
+	if size <= 0 || size > 200 {
+		size = 100
+	}
+
+	states := map[string]bool{}
+	for _, state := range strings.Split(params.Get("transactionStates"), ",") {
+		if state = strings.TrimSpace(strings.ToUpper(state)); state != "" {
+			states[state] = true
+		}
+	}
+	merchants := map[string]bool{}
+	for _, login := range strings.Split(params.Get("merchants"), ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			merchants[login] = true
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var matched []*order
+	now := time.Now()
+	for _, o := range g.orders {
+		g.expire(o, now)
+		if o.created.Before(from) || o.created.After(to) {
+			continue
+		}
+		if len(states) > 0 && !states[paymentState(o.status)] {
+			continue
+		}
+		if len(merchants) > 0 && !merchants[o.merchantLogin] {
+			continue
+		}
+		matched = append(matched, o)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].created.Before(matched[j].created) })
+
+	resp := alfapay.GetLastOrdersResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0", ErrorMessage: "Success"},
+		TotalCount:   len(matched),
+		Page:         page,
+		PageSize:     size,
+	}
+	for i := page * size; i >= 0 && i < len(matched) && i < (page+1)*size; i++ {
+		status := matched[i].response()
+		status.BaseResponse = alfapay.BaseResponse{}
+		status.PaymentAmountInfo.FeeAmount = g.fee(matched[i])
+		resp.Orders = append(resp.Orders, *status)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (g *Gateway) handleDecline(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), params.Get("orderNumber"))
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusRegistered && o.status != alfapay.OrderStatusACSAuthorization {
+		writeError(w, ErrorCodeInvalidState, "Only unpaid orders can be declined")
+		return
+	}
+	o.status = alfapay.OrderStatusDeclined
+	o.actionCodeDescription = "Declined by merchant"
+	writeOK(w)
+}
+
+func (g *Gateway) handleAddParams(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	var added map[string]string
+	if raw := params.Get("params"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &added); err != nil {
+			writeError(w, ErrorCodeInvalidParam, "params must be a JSON object")
+			return
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	names := make([]string, 0, len(added))
+	for name := range added {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o.setParam(name, added[name])
+	}
+	writeOK(w)
+}
+
+func (o *order) setParam(name, value string) {
+	for i := range o.params {
+		if o.params[i].Name == name {
+			o.params[i].Value = value
+			return
+		}
+	}
+	o.params = append(o.params, alfapay.OrderAddendum{Name: name, Value: value})
+}
+
+func (g *Gateway) handleDeposit(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	amount, _ := strconv.ParseInt(params.Get("amount"), 10, 64)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusPreAuthorized {
+		writeError(w, ErrorCodeInvalidState, "Order must be pre-authorized to be deposited")
+		return
+	}
+	if amount == 0 {
+		amount = o.approvedAmount
+	}
+	if amount < 0 || amount > o.approvedAmount {
+		writeError(w, ErrorCodeInvalidState, "Deposit amount exceeds the approved amount")
+		return
+	}
+
+	o.status = alfapay.OrderStatusFullyAuthorized
+	o.deposited = time.Now()
+	o.depositedAmount = amount
+	g.notify(o, operationDeposited, true)
+	writeOK(w)
+}
+
+func (g *Gateway) handleReverse(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusPreAuthorized {
+		writeError(w, ErrorCodeInvalidState, "Only pre-authorized orders can be reversed")
+		return
+	}
+
+	o.status = alfapay.OrderStatusCancelled
+	o.reversed = time.Now()
+	g.notify(o, operationReversed, true)
+	writeOK(w)
+}
+
+func (g *Gateway) handleRefund(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	amount, _ := strconv.ParseInt(params.Get("amount"), 10, 64)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if _, code, msg := g.refund(o, amount); code != "" {
+		writeError(w, code, msg)
+		return
+	}
+	writeOK(w)
+}
+
+func (g *Gateway) handlePaymentOrderBinding(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	g.payWithBinding(w, r, o, params.Get("bindingId"), params.Get("ip"))
+}
+
+func (g *Gateway) handleInstantPayment(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	number := params.Get("orderNumber")
+	amount, err := strconv.ParseInt(params.Get("amount"), 10, 64)
+	if number == "" || err != nil || amount <= 0 {
+		writeError(w, ErrorCodeInvalidParam, "orderNumber and a positive amount are required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.numbers[number]; exists {
+		writeError(w, ErrorCodeDuplicate, "Order with this number was already processed")
+		return
+	}
+	o := g.newOrder(number, amount)
+	o.returnURL = params.Get("returnUrl")
+	o.failURL = params.Get("failUrl")
+	o.description = params.Get("description")
+	o.email = params.Get("email")
+	o.phone = params.Get("phone")
+	o.expires = o.created.Add(DefaultSessionTimeout)
+	if currency := params.Get("currency"); currency != "" {
+		o.currency = currency
+	}
+
+	if params.Get("bindingId") == "" {
+		writeJSON(w, http.StatusOK, alfapay.InstantPaymentResponse{
+			BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+			OrderID:      o.id,
+			FormURL:      g.formURL(r, o),
+		})
+		return
+	}
+	g.payWithBinding(w, r, o, params.Get("bindingId"), params.Get("ip"))
+}
+
+// payWithBinding pays an order with a saved card and writes a PaymentFormResult. The caller must hold the lock.
+func (g *Gateway) payWithBinding(w http.ResponseWriter, r *http.Request, o *order, bindingID, ip string) {
+	b := g.bindings[bindingID]
+	if b == nil || !b.active {
+		writeError(w, ErrorCodeInvalidParam, "Binding not found")
+		return
+	}
+	if b.clientID != "" && o.clientID != "" && b.clientID != o.clientID {
+		writeError(w, ErrorCodeInvalidParam, "Binding belongs to another client")
+		return
+	}
+	if o.status != alfapay.OrderStatusRegistered {
+		writeError(w, ErrorCodeInvalidState, "Order is already processed")
+		return
+	}
+
+	o.bindingID = b.id
+	o.clientID = b.clientID
+	o.ip = ip
+	b.lastUsed = time.Now()
+
+	result := alfapay.PaymentFormResult{BaseResponse: alfapay.BaseResponse{ErrorCode: "0"}, OrderID: o.id}
+	if g.pay(o, b.card(), paymentWayBinding, true) {
+		result.AcsURL = withQuery(g.publicURL(r)+acsPath, url.Values{"mdOrder": {o.id}})
+		result.PaReq = o.id
+		result.TermURL = o.returnURL
+	} else {
+		result.Redirect = g.finishURL(o)
+		if o.status == alfapay.OrderStatusDeclined {
+			result.Info = o.actionCodeDescription
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (g *Gateway) handleFinish3DS(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusACSAuthorization {
+		writeError(w, ErrorCodeInvalidState, "Order is not waiting for 3-D Secure")
+		return
+	}
+
+	// Any PaRes other than "DECLINE" passes authentication
+	g.finishChallenge(o, params.Get("paRes") != "" && params.Get("paRes") != "DECLINE")
+	writeJSON(w, http.StatusOK, alfapay.PaymentFormResult{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		Redirect:     g.finishURL(o),
+	})
+}
+
+func (g *Gateway) handleVerifyEnrollment(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	pan := params.Get("pan")
+	if !luhn(pan) {
+		writeError(w, ErrorCodeInvalidParam, "Invalid card number")
+		return
+	}
+
+	enrolled := "N"
+	if g.scenario(pan) == ScenarioChallenge {
+		enrolled = "Y"
+	}
+	writeJSON(w, http.StatusOK, alfapay.VerifyEnrollmentResponse{
+		BaseResponse:       alfapay.BaseResponse{ErrorCode: "0"},
+		Enrolled:           enrolled,
+		EmitterName:        "MOCK BANK",
+		EmitterCountryCode: "RU",
+	})
+}
+
+// recurrentPaymentRequest is the body of recurrentPayment.do, which carries the credentials.
+type recurrentPaymentRequest struct {
+	alfapay.RecurrentPaymentRequest
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+func (g *Gateway) handleRecurrentPayment(w http.ResponseWriter, r *http.Request) {
+	var req recurrentPaymentRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	fail := func(code int, message string) {
+		writeJSON(w, http.StatusOK, alfapay.RecurrentPaymentResponse{
+			Error: &alfapay.RecurrentPaymentError{Code: code, Message: message, Description: message},
+		})
+	}
+	if !g.authorized(req.UserName, req.Password) {
+		fail(5, "Access denied")
+		return
+	}
+	if req.OrderNumber == "" || req.Amount <= 0 {
+		fail(5, "orderNumber and a positive amount are required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := g.bindings[req.BindingID]
+	if b == nil || !b.active {
+		fail(5, "Binding not found")
+		return
+	}
+	if _, exists := g.numbers[req.OrderNumber]; exists {
+		fail(1, "Order with this number was already processed")
+		return
+	}
+
+	o := g.newOrder(req.OrderNumber, req.Amount)
+	o.preAuth = req.PreAuth
+	o.description = req.Description
+	o.clientID = b.clientID
+	o.bindingID = b.id
+	o.callbackURL = req.DynamicCallbackURL
+	if req.Currency != "" {
+		o.currency = req.Currency
+	}
+	b.lastUsed = time.Now()
+
+	// Merchant-initiated payments are not authenticated with 3-D Secure
+	g.pay(o, b.card(), paymentWayBinding, false)
+
+	resp := alfapay.RecurrentPaymentResponse{OrderStatus: o.response()}
+	if o.status == alfapay.OrderStatusDeclined {
+		resp.Error = &alfapay.RecurrentPaymentError{
+			Code:        o.actionCode,
+			Message:     o.actionCodeDescription,
+			Description: o.actionCodeDescription,
+		}
+	} else {
+		resp.Success = true
+		resp.Data = &alfapay.RecurrentPaymentData{OrderID: o.id, OrderNumber: o.number, Amount: o.amount}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// finishURL returns where the payer is sent after a payment attempt.
+func (g *Gateway) finishURL(o *order) string {
+	target := o.returnURL
+	if o.status == alfapay.OrderStatusDeclined && o.failURL != "" {
+		target = o.failURL
+	}
+	return withQuery(target, url.Values{"orderId": {o.id}})
+}
+
+func luhn(pan string) bool {
+	if len(pan) < 12 || len(pan) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(pan) - 1; i >= 0; i-- {
+		d := int(pan[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
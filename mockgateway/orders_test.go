@@ -0,0 +1,84 @@
+package mockgateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+// timeoutError is a transport timeout, as reported by net/http.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// lostRegisterResponse forwards register.do but times out before the response arrives.
+type lostRegisterResponse struct{}
+
+func (lostRegisterResponse) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil || !strings.HasSuffix(r.URL.Path, "/register.do") {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, timeoutError{}
+}
+
+// registerCounter counts register.do requests that created an order.
+type registerCounter struct {
+	handler http.Handler
+	created atomic.Int32
+}
+
+func (c *registerCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, r)
+	if strings.HasSuffix(r.URL.Path, "/register.do") && strings.Contains(rec.Body.String(), `"orderId"`) {
+		c.created.Add(1)
+	}
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+func TestRegisterIdempotentAfterTimeout(t *testing.T) {
+	counter := &registerCounter{handler: mockgateway.New(mockgateway.Options{})}
+	srv := httptest.NewServer(counter)
+	defer srv.Close()
+
+	req := &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-TIMEOUT-1",
+		Amount:      150000,
+		ReturnURL:   "https://shop.example.com/return",
+	}
+	ctx := context.Background()
+
+	timingOut := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix),
+		alfapay.WithHTTPClient(&http.Client{Transport: lostRegisterResponse{}}))
+	first, err := timingOut.Orders.RegisterIdempotent(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	retry, err := client.Orders.RegisterIdempotent(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.OrderID == "" || retry.OrderID != first.OrderID {
+		t.Errorf("order IDs = %q then %q, want the same order", first.OrderID, retry.OrderID)
+	}
+	if n := counter.created.Load(); n != 1 {
+		t.Errorf("%d orders created, want 1", n)
+	}
+}
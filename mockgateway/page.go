@@ -0,0 +1,343 @@
+package mockgateway
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// Paths of the pages served to payers, relative to PathPrefix.
+const (
+	pagePath         = "/merchants/mock/payment_ru.html"
+	acsPath          = "/acs"
+	sbpPayPath       = "/sbp/pay"
+	sbpSubscribePath = "/sbp/subscribe"
+)
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 32em; margin: 2em auto; }
+label { display: block; margin: .5em 0; }
+input[type=text] { width: 100%; }
+.error { color: #c00; }
+table { border-collapse: collapse; font-size: .9em; }
+td { padding: .2em .6em; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Order}}<p>Order <b>{{.Order.Number}}</b>{{if .Order.Description}}: {{.Order.Description}}{{end}}</p>
+<p>Amount: <b id="amount">{{.Order.Amount}}</b></p>{{end}}
+{{if .Error}}<p class="error" id="error">{{.Error}}</p>{{end}}
+{{if .Message}}<p id="message">{{.Message}}</p>{{end}}
+{{if .Card}}<form method="post" id="payment-form">
+<input type="hidden" name="mdOrder" value="{{.Order.ID}}">
+<label>Card number <input type="text" name="pan" autocomplete="cc-number" value="{{.PAN}}"></label>
+<label>Expiry (MM/YY) <input type="text" name="expiry" autocomplete="cc-exp" value="12/30"></label>
+<label>CVC <input type="text" name="cvc" autocomplete="cc-csc" value="123"></label>
+<label>Cardholder <input type="text" name="holder" autocomplete="cc-name" value="TEST CARDHOLDER"></label>
+{{if .Order.ClientID}}<label><input type="checkbox" name="saveCard" value="true" checked> Save card</label>{{end}}
+<button type="submit" name="action" value="pay">Pay</button>
+<button type="submit" name="action" value="cancel">Cancel</button>
+</form>
+<h2>Test cards</h2>
+<table>{{range .Cards}}<tr><td><code>{{.PAN}}</code></td><td>{{.Scenario}}</td></tr>{{end}}</table>{{end}}
+{{if .Actions}}<form method="post" id="action-form">
+{{range $name, $value := .Hidden}}<input type="hidden" name="{{$name}}" value="{{$value}}">{{end}}
+{{range .Actions}}<button type="submit" name="action" value="{{.Value}}">{{.Label}}</button> {{end}}
+</form>{{end}}
+{{if .Continue}}<p><a href="{{.Continue}}" id="continue">Return to the store</a></p>{{end}}
+</body>
+</html>
+`))
+
+// pageData is rendered by pageTemplate.
+type pageData struct {
+	Title    string
+	Order    *pageOrder
+	Error    string
+	Message  string
+	Card     bool
+	PAN      string
+	Cards    []pageCard
+	Hidden   map[string]string
+	Actions  []pageAction
+	Continue string
+}
+
+type pageOrder struct {
+	ID, Number, Description, Amount, ClientID string
+}
+
+type pageCard struct {
+	PAN      string
+	Scenario Scenario
+}
+
+type pageAction struct {
+	Value, Label string
+}
+
+func newPageOrder(o *order) *pageOrder {
+	currency := "RUB"
+	if o.currency != "643" && o.currency != "" {
+		currency = o.currency
+	}
+	return &pageOrder{
+		ID:          o.id,
+		Number:      o.number,
+		Description: o.description,
+		Amount:      fmt.Sprintf("%d.%02d %s", o.amount/100, o.amount%100, currency),
+		ClientID:    o.clientID,
+	}
+}
+
+func renderPage(w http.ResponseWriter, status int, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = pageTemplate.Execute(w, data)
+}
+
+// formURL returns the payment page URL of an order.
+func (g *Gateway) formURL(r *http.Request, o *order) string {
+	return withQuery(g.publicURL(r)+pagePath, url.Values{"mdOrder": {o.id}})
+}
+
+// pageOrderLocked parses the form and finds the order of a page request, rendering an error page if it is unknown.
+// On success the lock is held and must be released by the caller.
+func (g *Gateway) pageOrderLocked(w http.ResponseWriter, r *http.Request) *order {
+	if err := r.ParseForm(); err != nil {
+		renderPage(w, http.StatusBadRequest, pageData{Title: "Payment", Error: "Invalid request"})
+		return nil
+	}
+	g.mu.Lock()
+	o := g.lookup(r.Form.Get("mdOrder"), "")
+	if o == nil {
+		g.mu.Unlock()
+		renderPage(w, http.StatusNotFound, pageData{Title: "Payment", Error: "Order not found"})
+		return nil
+	}
+	return o
+}
+
+// handlePaymentPage serves the hosted payment page at FormURL.
+func (g *Gateway) handlePaymentPage(w http.ResponseWriter, r *http.Request) {
+	o := g.pageOrderLocked(w, r)
+	if o == nil {
+		return
+	}
+	defer g.mu.Unlock()
+
+	data := pageData{Title: "Payment", Order: newPageOrder(o), Cards: g.testCards()}
+	if o.status != alfapay.OrderStatusRegistered {
+		data.Message = "The order is already processed: " + paymentState(o.status)
+		data.Continue = g.finishURL(o)
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+	if r.Method != http.MethodPost {
+		data.Card = true
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+
+	if r.Form.Get("action") == "cancel" {
+		g.declinePayment(o, nil, paymentWayCard, 0, "Cancelled by payer")
+		http.Redirect(w, r, g.finishURL(o), http.StatusSeeOther)
+		return
+	}
+
+	c, err := parseCard(r.Form)
+	if err != nil {
+		data.Card = true
+		data.PAN = r.Form.Get("pan")
+		data.Error = err.Error()
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+
+	o.ip = clientIP(r)
+	if g.pay(o, c, paymentWayCard, true) {
+		o.saveCard = r.Form.Get("saveCard") == "true"
+		http.Redirect(w, r, withQuery(g.publicURL(r)+acsPath, url.Values{"mdOrder": {o.id}}), http.StatusSeeOther)
+		return
+	}
+	if r.Form.Get("saveCard") == "true" && o.status != alfapay.OrderStatusDeclined {
+		g.saveCard(o)
+	}
+	http.Redirect(w, r, g.finishURL(o), http.StatusSeeOther)
+}
+
+// handleACSPage serves the 3-D Secure challenge of cards with ScenarioChallenge.
+func (g *Gateway) handleACSPage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err == nil && r.Form.Get("mdOrder") == "" {
+		// Issuer-style request with the order ID in PaReq or MD
+		if id := r.Form.Get("MD"); id != "" {
+			r.Form.Set("mdOrder", id)
+		} else {
+			r.Form.Set("mdOrder", r.Form.Get("PaReq"))
+		}
+	}
+	o := g.pageOrderLocked(w, r)
+	if o == nil {
+		return
+	}
+	defer g.mu.Unlock()
+
+	data := pageData{Title: "3-D Secure", Order: newPageOrder(o)}
+	if o.status != alfapay.OrderStatusACSAuthorization {
+		data.Message = "The order is not waiting for authentication"
+		data.Continue = g.finishURL(o)
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+	if r.Method != http.MethodPost {
+		data.Message = "Card " + o.card.masked() + ": confirm the payment with the one-time code."
+		data.Hidden = map[string]string{"mdOrder": o.id}
+		data.Actions = []pageAction{{"confirm", "Confirm"}, {"fail", "Fail authentication"}}
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+
+	g.finishChallenge(o, r.Form.Get("action") == "confirm")
+	if o.status != alfapay.OrderStatusDeclined && o.saveCard {
+		g.saveCard(o)
+	}
+	http.Redirect(w, r, g.finishURL(o), http.StatusSeeOther)
+}
+
+// handleSBPPayPage emulates the payer's banking app for SBP QR codes.
+func (g *Gateway) handleSBPPayPage(w http.ResponseWriter, r *http.Request) {
+	o := g.pageOrderLocked(w, r)
+	if o == nil {
+		return
+	}
+	defer g.mu.Unlock()
+
+	data := pageData{Title: "SBP", Order: newPageOrder(o)}
+	if o.status != alfapay.OrderStatusRegistered {
+		data.Message = "The order is already processed: " + paymentState(o.status)
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+	if r.Method != http.MethodPost {
+		data.Hidden = map[string]string{"mdOrder": o.id}
+		data.Actions = []pageAction{{"pay", "Pay"}, {"reject", "Reject"}}
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+
+	if r.Form.Get("action") == "pay" {
+		g.paySBP(o, paymentWaySBP)
+		data.Message = "Payment completed"
+	} else {
+		g.declinePayment(o, nil, paymentWaySBP, 0, "Rejected by payer")
+		data.Message = "Payment rejected"
+	}
+	renderPage(w, http.StatusOK, data)
+}
+
+// handleSBPSubscribePage emulates the payer's consent to an SBP subscription.
+func (g *Gateway) handleSBPSubscribePage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderPage(w, http.StatusBadRequest, pageData{Title: "SBP", Error: "Invalid request"})
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := g.subscriptions[r.Form.Get("qrId")]
+	if s == nil {
+		renderPage(w, http.StatusNotFound, pageData{Title: "SBP", Error: "Subscription not found"})
+		return
+	}
+
+	data := pageData{Title: "SBP subscription", Message: s.purpose}
+	if o := g.orders[s.orderID]; o != nil {
+		data.Order = newPageOrder(o)
+	}
+	if s.status != alfapay.SBPSubscriptionStatusCreated {
+		data.Message = "The subscription is " + string(s.status)
+		data.Continue = s.redirectURL
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+	if r.Method != http.MethodPost {
+		data.Hidden = map[string]string{"qrId": s.id}
+		data.Actions = []pageAction{{"consent", "Consent"}, {"reject", "Reject"}}
+		renderPage(w, http.StatusOK, data)
+		return
+	}
+
+	if r.Form.Get("action") == "consent" {
+		g.activateSubscription(s)
+	} else {
+		s.status = alfapay.SBPSubscriptionStatusRejected
+	}
+	if s.redirectURL != "" {
+		http.Redirect(w, r, s.redirectURL, http.StatusSeeOther)
+		return
+	}
+	data.Message = "The subscription is " + string(s.status)
+	renderPage(w, http.StatusOK, data)
+}
+
+// testCards returns the configured test cards for display, sorted by scenario.
+func (g *Gateway) testCards() []pageCard {
+	cards := make([]pageCard, 0, len(g.opts.Cards))
+	for pan, scenario := range g.opts.Cards {
+		cards = append(cards, pageCard{PAN: pan, Scenario: scenario})
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Scenario != cards[j].Scenario {
+			return cards[i].Scenario > cards[j].Scenario
+		}
+		return cards[i].PAN < cards[j].PAN
+	})
+	return cards
+}
+
+// parseCard validates the card fields of the payment form.
+func parseCard(form url.Values) (*card, error) {
+	pan := strings.NewReplacer(" ", "", "-", "").Replace(form.Get("pan"))
+	if !luhn(pan) {
+		return nil, fmt.Errorf("invalid card number")
+	}
+
+	var month, year int
+	if _, err := fmt.Sscanf(strings.TrimSpace(form.Get("expiry")), "%d/%d", &month, &year); err != nil || month < 1 || month > 12 {
+		return nil, fmt.Errorf("expiry must be in the format MM/YY")
+	}
+	if year < 100 {
+		year += 2000
+	}
+	expiry := fmt.Sprintf("%04d%02d", year, month)
+	if expires, _ := alfapay.ParseExpiry(expiry); !time.Now().Before(expires) {
+		return nil, fmt.Errorf("the card has expired")
+	}
+
+	cvc := form.Get("cvc")
+	if len(cvc) < 3 || len(cvc) > 4 {
+		return nil, fmt.Errorf("invalid CVC")
+	}
+	return &card{pan: pan, expiry: expiry, holder: strings.ToUpper(strings.TrimSpace(form.Get("holder")))}, nil
+}
+
+func clientIP(r *http.Request) string {
+	host := r.RemoteAddr
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		host = host[:i]
+	}
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,239 @@
+package mockgateway_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+func newPayout(orderNumber string) *alfapay.SBPB2CPayoutRequest {
+	return &alfapay.SBPB2CPayoutRequest{
+		OrderNumber: orderNumber,
+		Amount:      50000,
+		RecipientParams: &alfapay.SBPB2CRecipientParams{
+			BankID: "100000000008",
+			Phone:  "79001234567",
+			Name:   &alfapay.SBPB2CRecipientName{FirstName: "Иван", MiddleName: "Иванович", LastName: "Иванов"},
+		},
+	}
+}
+
+func TestPayoutCheckForms(t *testing.T) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	check, err := client.SBP.B2CPreCheckPayout(ctx, newPayout("PAYOUT-CHECK-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.IsSuccess() || check.RecipientName != "Иван Иванович И." {
+		t.Fatalf("pre-check = %+v, want the masked recipient name", check)
+	}
+
+	result, err := client.Payouts.Send(ctx, &alfapay.PayoutRequest{
+		Payout:       newPayout("PAYOUT-CHECK-1"),
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The deprecated orderId form still reports the payout status
+	status, err := client.SBP.B2CCheckPayout(ctx, result.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.IsSuccess() || status.OrderID != result.OrderID || alfapay.PayoutStatus(status.OrderStatus) != alfapay.PayoutStatusSuccess {
+		t.Errorf("check by order ID = %+v, want a successful payout %s", status, result.OrderID)
+	}
+}
+
+// performCounter counts performPayout.do requests reaching the gateway.
+type performCounter struct {
+	handler  http.Handler
+	performs atomic.Int32
+}
+
+func (c *performCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/performPayout.do") {
+		c.performs.Add(1)
+	}
+	c.handler.ServeHTTP(w, r)
+}
+
+// lostPerformResponse forwards performPayout.do but drops the response, as a crash right after sending would.
+type lostPerformResponse struct{}
+
+func (lostPerformResponse) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil || !strings.HasSuffix(r.URL.Path, "/performPayout.do") {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, errors.New("connection reset")
+}
+
+// cancelOnCheck cancels the batch when the recipient of the given order number is checked.
+type cancelOnCheck struct {
+	orderNumber string
+	cancel      context.CancelFunc
+}
+
+func (c *cancelOnCheck) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, "/checkPayout.do") && r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(string(body), c.orderNumber) {
+			c.cancel()
+			return nil, context.Canceled
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func batchItems(orderNumbers ...string) []alfapay.PayoutBatchItem {
+	items := make([]alfapay.PayoutBatchItem, len(orderNumbers))
+	for i, number := range orderNumbers {
+		items[i] = alfapay.PayoutBatchItem{Line: i + 2, Payout: newPayout(number)}
+	}
+	return items
+}
+
+func TestPayoutBatchResumeAfterPerforming(t *testing.T) {
+	counter := &performCounter{handler: mockgateway.New(mockgateway.Options{})}
+	srv := httptest.NewServer(counter)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store, err := alfapay.NewFilePayoutCheckpointStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := batchItems("BATCH-PERF-1")
+	opts := alfapay.PayoutBatchOptions{Store: store, PollInterval: time.Millisecond}
+	ctx := context.Background()
+
+	crashing := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix),
+		alfapay.WithHTTPClient(&http.Client{Transport: lostPerformResponse{}}))
+	report, err := crashing.Payouts.SendBatch(ctx, "batch", items, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pending != 1 || report.Failed != 0 {
+		t.Fatalf("first run: pending %d, failed %d; want the line pending", report.Pending, report.Failed)
+	}
+
+	// Restart: the checkpoint is read back from disk
+	reopened, err := alfapay.NewFilePayoutCheckpointStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := reopened.Load(ctx, "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := saved["BATCH-PERF-1"]; !line.Performing || line.Line != 2 || line.Amount != 50000 {
+		t.Fatalf("checkpoint = %+v, want line 2 marked performing", line)
+	}
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	opts.Store = reopened
+	report, err = client.Payouts.SendBatch(ctx, "batch", items, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 1 || report.Results[0].OrderID == "" {
+		t.Errorf("resume: %+v, want the performed payout tracked to success", report.Results[0])
+	}
+	if n := counter.performs.Load(); n != 1 {
+		t.Errorf("payout performed %d times, want 1", n)
+	}
+}
+
+func TestPayoutBatchResumeAfterCancel(t *testing.T) {
+	counter := &performCounter{handler: mockgateway.New(mockgateway.Options{})}
+	srv := httptest.NewServer(counter)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store, err := alfapay.NewFilePayoutCheckpointStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := batchItems("BATCH-CANCEL-1", "BATCH-CANCEL-2", "BATCH-CANCEL-3")
+
+	// Cancel during the recipient check of the second line, before anything is performed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix),
+		alfapay.WithHTTPClient(&http.Client{Transport: &cancelOnCheck{orderNumber: "BATCH-CANCEL-2", cancel: cancel}}))
+	opts := alfapay.PayoutBatchOptions{Concurrency: 1, Store: store, PollInterval: time.Millisecond}
+	report, err := client.Payouts.SendBatch(ctx, "batch", items, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if report.Succeeded != 1 || report.Pending != 2 || report.Failed != 0 {
+		t.Fatalf("cancelled run: succeeded %d, pending %d, failed %d; want 1, 2, 0",
+			report.Succeeded, report.Pending, report.Failed)
+	}
+
+	reopened, err := alfapay.NewFilePayoutCheckpointStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := reopened.Load(context.Background(), "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := saved["BATCH-CANCEL-1"]; first.Status != alfapay.PayoutStatusSuccess || first.OrderID != report.Results[0].OrderID {
+		t.Fatalf("checkpoint of the first line = %+v, want the successful payout %s", first, report.Results[0].OrderID)
+	}
+
+	client = alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	opts.Store = reopened
+	report, err = client.Payouts.SendBatch(context.Background(), "batch", items, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 3 || !report.Results[0].Resumed {
+		t.Errorf("resume: succeeded %d, first line resumed %v; want 3 and true", report.Succeeded, report.Results[0].Resumed)
+	}
+	if n := counter.performs.Load(); n != 3 {
+		t.Errorf("payouts performed %d times, want 3", n)
+	}
+}
+
+func TestPayoutBatchRateLimitFirstLine(t *testing.T) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	start := time.Now()
+	report, err := client.Payouts.SendBatch(context.Background(), "batch", batchItems("BATCH-RATE-1"),
+		alfapay.PayoutBatchOptions{RatePerSecond: 0.2, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 1 {
+		t.Fatalf("succeeded %d, want 1", report.Succeeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("first line waited %v for the rate limit", elapsed)
+	}
+}
@@ -0,0 +1,139 @@
+package mockgateway_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+// payOrder registers an order and pays it with a test card.
+func payOrder(t *testing.T, srvURL string, client *alfapay.Client, orderNumber string, amount int64) string {
+	t.Helper()
+	order, err := client.Orders.Register(context.Background(), &alfapay.RegisterOrderRequest{
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		ReturnURL:   "https://shop.example.com/return",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srvURL+"/admin/orders/"+order.OrderID+"/pay", "application/json",
+		strings.NewReader(`{"pan": "4111111111111111"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return order.OrderID
+}
+
+func TestBatchRefund(t *testing.T) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	full := payOrder(t, srv.URL, client, "REFUND-BATCH-1", 10000)
+	partial := payOrder(t, srv.URL, client, "REFUND-BATCH-2", 10000)
+	refunded := payOrder(t, srv.URL, client, "REFUND-BATCH-3", 10000)
+	over := payOrder(t, srv.URL, client, "REFUND-BATCH-4", 10000)
+	if _, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: refunded, Amount: 10000}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := client.Refunds.BatchRefund(ctx, []alfapay.BatchRefundItem{
+		{OrderID: full},
+		{OrderID: partial, Amount: 4000},
+		{OrderID: refunded},
+		{OrderID: over, Amount: 10001},
+		{OrderID: partial, Amount: 4000},
+	}, alfapay.BatchRefundOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []alfapay.BatchRefundOutcome{
+		alfapay.BatchRefundRefunded,
+		alfapay.BatchRefundRefunded,
+		alfapay.BatchRefundSkipped,
+		alfapay.BatchRefundFailed,
+		alfapay.BatchRefundFailed,
+	}
+	for i, result := range report.Results {
+		if result.Outcome != want[i] {
+			t.Errorf("item %d (%s): outcome %s (%s), want %s", i, result.OrderID, result.Outcome, result.Reason, want[i])
+		}
+	}
+	if report.Results[4].Reason != "duplicate order in batch" {
+		t.Errorf("duplicate reason = %q", report.Results[4].Reason)
+	}
+	if report.Refunded != 2 || report.Skipped != 1 || report.Failed != 2 || report.RefundedAmount != 14000 {
+		t.Errorf("report = %+v, want 2 refunded for 14000, 1 skipped, 2 failed", report)
+	}
+
+	// The over-amount refund was rejected before reaching the gateway
+	status, err := client.Status.GetExtended(ctx, &alfapay.GetOrderStatusRequest{OrderID: over})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PaymentAmountInfo.RefundedAmount != 0 {
+		t.Errorf("over-amount order refunded %d, want 0", status.PaymentAmountInfo.RefundedAmount)
+	}
+}
+
+// failingRefunds fails refund.do requests; with forward set, the request reaches the gateway first.
+type failingRefunds struct {
+	forward bool
+}
+
+func (f failingRefunds) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(r.URL.Path, "/refund.do") {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	if f.forward {
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+	}
+	return nil, errors.New("connection reset")
+}
+
+func TestBatchRefundTransportError(t *testing.T) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	setup := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	lost := payOrder(t, srv.URL, setup, "REFUND-LOST-1", 10000)
+	unsent := payOrder(t, srv.URL, setup, "REFUND-UNSENT-1", 10000)
+
+	tests := []struct {
+		orderID string
+		forward bool
+		want    alfapay.BatchRefundOutcome
+	}{
+		{lost, true, alfapay.BatchRefundRefunded},   // The refund went through, only the response was lost
+		{unsent, false, alfapay.BatchRefundUnknown}, // Not refunded yet, but the request may still arrive
+	}
+	for _, tt := range tests {
+		client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix),
+			alfapay.WithHTTPClient(&http.Client{Transport: failingRefunds{forward: tt.forward}}))
+		report, err := client.Refunds.BatchRefund(context.Background(), []alfapay.BatchRefundItem{{OrderID: tt.orderID, Amount: 3000}}, alfapay.BatchRefundOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := report.Results[0]; got.Outcome != tt.want {
+			t.Errorf("forwarded %v: outcome %s (%s), want %s", tt.forward, got.Outcome, got.Reason, tt.want)
+		}
+		if tt.want == alfapay.BatchRefundUnknown && report.Unknown != 1 {
+			t.Errorf("report = %+v, want 1 unknown", report)
+		}
+	}
+}
@@ -0,0 +1,627 @@
+package mockgateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// mockBankID is the NSPK member ID reported as the merchant's bank (Alfa-Bank).
+const mockBankID = "100000000008"
+
+// sbpBinding is a payer's consent to SBP recurrent payments.
+type sbpBinding struct {
+	id          string
+	clientID    string
+	bankName    string
+	maskedPhone string
+	active      bool
+	created     time.Time
+}
+
+// subscription is a QR code asking the payer to consent to an SBP subscription.
+type subscription struct {
+	id          string
+	clientID    string
+	orderID     string
+	purpose     string
+	redirectURL string
+	status      alfapay.SBPSubscriptionStatus
+	bindingID   string
+}
+
+// payout is the state of a B2C payout order.
+type payout struct {
+	status        alfapay.PayoutStatus
+	description   string
+	phone         string
+	bankID        string
+	recipientName string
+	held          bool // Status set via the admin API, not completed by status checks
+}
+
+// Recipient phones ending in these digits fail payouts, to exercise error handling.
+const (
+	payoutDeclinedSuffix = "0000" // Recipient not found at the bank
+	payoutErrorSuffix    = "9999" // Technical failure
+)
+
+// paySBP completes an SBP payment. SBP payments are always single-stage. The caller must hold the lock.
+func (g *Gateway) paySBP(o *order, paymentWay string) {
+	o.preAuth = false
+	g.authorize(o, nil, paymentWay)
+}
+
+// activateSubscription records the payer's consent and pays the linked order. The caller must hold the lock.
+func (g *Gateway) activateSubscription(s *subscription) {
+	b := &sbpBinding{
+		id:          newID(),
+		clientID:    s.clientID,
+		bankName:    "Альфа-Банк",
+		maskedPhone: "+7 *** ***-**-00",
+		active:      true,
+		created:     time.Now(),
+	}
+	g.sbpBindings[b.id] = b
+	s.status = alfapay.SBPSubscriptionStatusActive
+	s.bindingID = b.id
+
+	if o := g.orders[s.orderID]; o != nil && o.status == alfapay.OrderStatusRegistered {
+		g.paySBP(o, paymentWaySBP)
+	}
+}
+
+// sbpQR builds the payload, link and optional image of a QR code for an amount.
+func (g *Gateway) sbpQR(params url.Values, amount int64, link string) (payload, qrImage string) {
+	p := &alfapay.SBPPayload{
+		QRCID:    strings.ToUpper(strings.ReplaceAll(newID(), "-", "")),
+		Type:     alfapay.SBPQRTypeDynamic,
+		BankID:   mockBankID,
+		Amount:   amount,
+		Currency: "RUB",
+	}
+	if amount == 0 {
+		p.Type = alfapay.SBPQRTypeStatic
+		p.Currency = ""
+	}
+	payload = p.String()
+
+	if params.Get("qrFormat") != "matrix" {
+		size, _ := strconv.Atoi(params.Get("qrWidth"))
+		if size <= 0 {
+			size = 256
+		}
+		// The mock does not encode QR codes; QRURL carries the link of the pay page instead
+		qrImage = placeholderQR(size)
+	}
+	return payload, qrImage
+}
+
+// placeholderQR returns a base64 PNG of a blank framed square of the given size.
+func placeholderQR(size int) string {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	border := size / 16
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x < border || y < border || x >= size-border || y >= size-border {
+				continue
+			}
+			img.SetGray(x, y, color.Gray{Y: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func (g *Gateway) handleSBPGetQR(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusRegistered {
+		writeError(w, ErrorCodeInvalidState, "Order is already processed")
+		return
+	}
+
+	link := withQuery(g.publicURL(r)+sbpPayPath, url.Values{"mdOrder": {o.id}})
+	payload, image := g.sbpQR(params, o.amount, link)
+	writeJSON(w, http.StatusOK, alfapay.SBPGetQRResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		QRImage:      image,
+		Payload:      payload,
+		QRURL:        link,
+	})
+}
+
+func (g *Gateway) handleSBPQRStatus(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, alfapay.SBPQRStatusResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  o.status,
+	})
+}
+
+func (g *Gateway) handleSBPRejectQR(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusRegistered {
+		writeError(w, ErrorCodeInvalidState, "Order is already processed")
+		return
+	}
+	o.status = alfapay.OrderStatusDeclined
+	o.actionCodeDescription = "QR code rejected by merchant"
+	writeOK(w)
+}
+
+func (g *Gateway) handleSBPBind(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("mdOrder"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.paymentWay != paymentWaySBP || o.status != alfapay.OrderStatusFullyAuthorized {
+		writeError(w, ErrorCodeInvalidState, "Order must be paid via SBP")
+		return
+	}
+	if o.clientID == "" {
+		writeError(w, ErrorCodeInvalidParam, "Order has no clientId")
+		return
+	}
+
+	b := &sbpBinding{
+		id:          newID(),
+		clientID:    o.clientID,
+		bankName:    "Альфа-Банк",
+		maskedPhone: "+7 *** ***-**-00",
+		active:      true,
+		created:     time.Now(),
+	}
+	g.sbpBindings[b.id] = b
+	writeJSON(w, http.StatusOK, alfapay.SBPBindResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		BindingID:    b.id,
+	})
+}
+
+func (g *Gateway) handleSBPUnbind(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := g.sbpBindings[params.Get("bindingId")]
+	if b == nil || !b.active {
+		writeError(w, ErrorCodeNotFound, "Binding not found")
+		return
+	}
+	b.active = false
+	for _, s := range g.subscriptions {
+		if s.bindingID == b.id {
+			s.status = alfapay.SBPSubscriptionStatusDeleted
+		}
+	}
+	writeOK(w)
+}
+
+func (g *Gateway) handleSBPGetBindings(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	clientID := params.Get("clientId")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var matched []*sbpBinding
+	for _, b := range g.sbpBindings {
+		if b.active && b.clientID == clientID {
+			matched = append(matched, b)
+		}
+	}
+	if len(matched) == 0 {
+		writeError(w, ErrorCodeNotFound, "Information not found")
+		return
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].created.Before(matched[j].created) })
+
+	resp := alfapay.SBPGetBindingsResponse{BaseResponse: alfapay.BaseResponse{ErrorCode: "0"}}
+	for _, b := range matched {
+		resp.Bindings = append(resp.Bindings, alfapay.SBPBinding{
+			BindingID:   b.id,
+			BankName:    b.bankName,
+			MaskedPhone: b.maskedPhone,
+			CreatedDate: millis(b.created),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (g *Gateway) handleSBPSubscriptionQR(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	if params.Get("clientId") == "" {
+		writeError(w, ErrorCodeInvalidParam, "clientId is required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := &subscription{
+		id:          newID(),
+		clientID:    params.Get("clientId"),
+		purpose:     params.Get("subscriptionPurpose"),
+		redirectURL: params.Get("redirectUrl"),
+		status:      alfapay.SBPSubscriptionStatusCreated,
+	}
+	var amount int64
+	if mdOrder := params.Get("mdOrder"); mdOrder != "" {
+		o := g.lookup(mdOrder, "")
+		if o == nil {
+			writeError(w, ErrorCodeUnknownOrder, "Order not found")
+			return
+		}
+		s.orderID = o.id
+		amount = o.amount
+	}
+	g.subscriptions[s.id] = s
+
+	link := withQuery(g.publicURL(r)+sbpSubscribePath, url.Values{"qrId": {s.id}})
+	payload, image := g.sbpQR(params, amount, link)
+	writeJSON(w, http.StatusOK, alfapay.SBPSubscriptionQRResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		QRID:         s.id,
+		QRImage:      image,
+		Payload:      payload,
+		QRURL:        link,
+	})
+}
+
+func (g *Gateway) handleSBPSubscriptionStatus(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := g.subscriptions[params.Get("qrId")]
+	if s == nil {
+		writeError(w, ErrorCodeNotFound, "Subscription not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, alfapay.SBPSubscriptionStatusResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		QRID:         s.id,
+		Status:       s.status,
+		BindingID:    s.bindingID,
+	})
+}
+
+func (g *Gateway) handleSBPRecurrent(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+	number := params.Get("orderNumber")
+	amount, err := strconv.ParseInt(params.Get("amount"), 10, 64)
+	if number == "" || err != nil || amount <= 0 {
+		writeError(w, ErrorCodeInvalidParam, "orderNumber and a positive amount are required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := g.sbpBindings[params.Get("bindingId")]
+	if b == nil || !b.active {
+		writeError(w, ErrorCodeNotFound, "Binding not found")
+		return
+	}
+	if _, exists := g.numbers[number]; exists {
+		writeError(w, ErrorCodeDuplicate, "Order with this number was already processed")
+		return
+	}
+
+	o := g.newOrder(number, amount)
+	o.description = params.Get("description")
+	o.clientID = b.clientID
+	o.callbackURL = params.Get("dynamicCallbackUrl")
+	if currency := params.Get("currency"); currency != "" {
+		o.currency = currency
+	}
+	g.paySBP(o, paymentWaySBPBinding)
+
+	writeJSON(w, http.StatusOK, alfapay.SBPRecurrentPaymentResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  o.status,
+	})
+}
+
+func (g *Gateway) handleSBPMembers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.formParams(w, r); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, alfapay.SBPBanksResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		Banks:        g.banks.All(),
+	})
+}
+
+func (g *Gateway) handleB2BPayload(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	link := withQuery(g.publicURL(r)+sbpPayPath, url.Values{"mdOrder": {o.id}})
+	payload, _ := g.sbpQR(url.Values{"qrFormat": {"matrix"}}, o.amount, link)
+	writeJSON(w, http.StatusOK, alfapay.SBPB2BPayloadResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		Payload:      payload,
+		QRURL:        link,
+		OrderID:      o.id,
+	})
+}
+
+func (g *Gateway) handleB2BPerform(w http.ResponseWriter, r *http.Request) {
+	var req alfapay.SBPB2BPerformRequest
+	if !g.jsonBody(w, r, &req) {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(req.OrderID, "")
+	if o == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Order not found")
+		return
+	}
+	if o.status != alfapay.OrderStatusRegistered {
+		writeError(w, ErrorCodeInvalidState, "Order is already processed")
+		return
+	}
+	if req.Amount != o.amount {
+		writeError(w, ErrorCodeInvalidParam, "Amount does not match the order")
+		return
+	}
+	g.paySBP(o, paymentWaySBPB2B)
+	writeJSON(w, http.StatusOK, alfapay.SBPB2BPerformResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  strconv.Itoa(int(o.status)),
+	})
+}
+
+func (g *Gateway) handleB2CCheckPayout(w http.ResponseWriter, r *http.Request) {
+	// The deprecated form of the call passes an orderId instead of a JSON payout request
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		g.handleB2CCheckPayoutStatus(w, r)
+		return
+	}
+
+	var req alfapay.SBPB2CPayoutRequest
+	if !g.jsonBody(w, r, &req) {
+		return
+	}
+	recipient := req.RecipientParams
+	if recipient == nil || recipient.Phone == "" || recipient.BankID == "" {
+		writeError(w, ErrorCodeInvalidParam, "recipientParams.phone and recipientParams.bankId are required")
+		return
+	}
+	if strings.HasSuffix(recipient.Phone, payoutDeclinedSuffix) {
+		writeError(w, ErrorCodeNotFound, "Recipient not found")
+		return
+	}
+
+	resp := alfapay.SBPB2CCheckPayoutResponse{
+		BaseResponse:  alfapay.BaseResponse{ErrorCode: "0"},
+		OrderStatus:   string(alfapay.PayoutStatusCreated),
+		Amount:        req.Amount,
+		RecipientName: recipientName(recipient.Name),
+	}
+	if bank, ok := g.banks.ByMemberID(recipient.BankID); ok {
+		resp.BankName = bank.Name
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleB2CCheckPayoutStatus serves checkPayout.do called with an orderId: the status of a performed payout.
+func (g *Gateway) handleB2CCheckPayoutStatus(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil || o.payout == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Payout not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, alfapay.SBPB2CCheckPayoutResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  string(o.payout.status),
+		Amount:       o.amount,
+	})
+}
+
+// recipientName masks a name as banks do: first name, patronymic and last name initial.
+func recipientName(name *alfapay.SBPB2CRecipientName) string {
+	if name == nil || name.FirstName == "" {
+		return "Иван Иванович И."
+	}
+	parts := []string{name.FirstName}
+	if name.MiddleName != "" {
+		parts = append(parts, name.MiddleName)
+	}
+	if last := []rune(name.LastName); len(last) > 0 {
+		parts = append(parts, string(last[0])+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (g *Gateway) handleB2CPerformPayout(w http.ResponseWriter, r *http.Request) {
+	var req alfapay.SBPB2CPayoutRequest
+	if !g.jsonBody(w, r, &req) {
+		return
+	}
+	if req.OrderNumber == "" || req.Amount <= 0 || req.RecipientParams == nil || req.RecipientParams.Phone == "" {
+		writeError(w, ErrorCodeInvalidParam, "orderNumber, a positive amount and recipientParams.phone are required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.numbers[req.OrderNumber]; exists {
+		writeError(w, ErrorCodeDuplicate, "Order with this number was already processed")
+		return
+	}
+
+	o := g.newOrder(req.OrderNumber, req.Amount)
+	o.description = req.Purpose
+	o.paymentWay = paymentWaySBPB2CPayout
+	if req.Currency != "" {
+		o.currency = req.Currency
+	}
+	o.payout = &payout{
+		status:        alfapay.PayoutStatusInProgress,
+		phone:         req.RecipientParams.Phone,
+		bankID:        req.RecipientParams.BankID,
+		recipientName: recipientName(req.RecipientParams.Name),
+	}
+	writeJSON(w, http.StatusOK, alfapay.SBPB2CPayoutResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  string(o.payout.status),
+	})
+}
+
+// handleB2CPayoutStatus serves getPayoutStatus.do.
+// A payout in progress completes on the first status check, unless its status was set via the admin API.
+func (g *Gateway) handleB2CPayoutStatus(w http.ResponseWriter, r *http.Request) {
+	params, ok := g.formParams(w, r)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o := g.lookup(params.Get("orderId"), "")
+	if o == nil || o.payout == nil {
+		writeError(w, ErrorCodeUnknownOrder, "Payout not found")
+		return
+	}
+	if o.payout.status == alfapay.PayoutStatusInProgress && !o.payout.held {
+		switch {
+		case strings.HasSuffix(o.payout.phone, payoutDeclinedSuffix):
+			g.setPayoutStatus(o, alfapay.PayoutStatusDeclined, "Recipient not found")
+		case strings.HasSuffix(o.payout.phone, payoutErrorSuffix):
+			g.setPayoutStatus(o, alfapay.PayoutStatusError, "Technical error")
+		default:
+			g.setPayoutStatus(o, alfapay.PayoutStatusSuccess, "")
+		}
+	}
+
+	writeJSON(w, http.StatusOK, alfapay.SBPB2CPayoutStatusResponse{
+		BaseResponse: alfapay.BaseResponse{ErrorCode: "0"},
+		OrderID:      o.id,
+		OrderStatus:  string(o.payout.status),
+		Amount:       o.amount,
+		StatusInfo:   &alfapay.SBPB2CStatusInfo{Status: string(o.payout.status), Description: o.payout.description},
+	})
+}
+
+// setPayoutStatus updates a payout and the order status reported by getOrderStatusExtended.
+// The caller must hold the lock.
+func (g *Gateway) setPayoutStatus(o *order, status alfapay.PayoutStatus, description string) {
+	o.payout.status = status
+	o.payout.description = description
+	switch status {
+	case alfapay.PayoutStatusSuccess:
+		o.status = alfapay.OrderStatusFullyAuthorized
+		o.deposited = time.Now()
+		o.depositedAmount = o.amount
+	case alfapay.PayoutStatusDeclined, alfapay.PayoutStatusError:
+		o.status = alfapay.OrderStatusDeclined
+		o.actionCodeDescription = description
+	default:
+		o.status = alfapay.OrderStatusRegistered
+	}
+}
@@ -0,0 +1,139 @@
+package mockgateway
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// walletKind identifies a wallet endpoint.
+type walletKind int
+
+const (
+	walletApplePay walletKind = iota
+	walletGooglePay
+	walletSamsungPay
+	walletMirPay       // Returns a payment link; the payer confirms in the MIR Pay app
+	walletMirPayDirect // Paid at once
+	walletYandexPay
+)
+
+// Wallet payment tokens containing this marker are declined, to exercise error handling.
+const walletDeclineToken = "decline"
+
+// walletRequest holds the fields shared by the wallet payment requests.
+type walletRequest struct {
+	Merchant     string `json:"merchant"`
+	OrderNumber  string `json:"orderNumber"`
+	PaymentToken string `json:"paymentToken"`
+	Amount       int64  `json:"amount"`
+	CurrencyCode string `json:"currencyCode"`
+	IP           string `json:"ip"`
+	ReturnURL    string `json:"returnUrl"`
+	FailURL      string `json:"failUrl"`
+	Description  string `json:"description"`
+	ClientID     string `json:"clientId"`
+	PreAuth      bool   `json:"preAuth"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+}
+
+// walletResponse is the common layout of wallet responses; data carries the provider-specific fields.
+type walletResponse struct {
+	Success     bool                                    `json:"success"`
+	Data        map[string]string                       `json:"data,omitempty"`
+	Error       *alfapay.WalletError                    `json:"error,omitempty"`
+	OrderStatus *alfapay.GetOrderStatusExtendedResponse `json:"orderStatus,omitempty"`
+}
+
+// walletHandler returns the handler of a wallet payment endpoint. Wallet requests carry
+// the merchant login in the body instead of credentials.
+func (g *Gateway) walletHandler(kind walletKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req walletRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		fail := func(code int, message string) {
+			writeJSON(w, http.StatusOK, walletResponse{
+				Error: &alfapay.WalletError{Code: code, Message: message, Description: message},
+			})
+		}
+		if req.Merchant == "" || (g.opts.UserName != "" && req.Merchant != g.opts.UserName) {
+			fail(5, "Access denied")
+			return
+		}
+		if req.OrderNumber == "" {
+			fail(5, "orderNumber is required")
+			return
+		}
+		if kind != walletMirPay && kind != walletMirPayDirect && req.PaymentToken == "" {
+			fail(5, "paymentToken is required")
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if _, exists := g.numbers[req.OrderNumber]; exists {
+			fail(1, "Order with this number was already processed")
+			return
+		}
+
+		amount := req.Amount
+		if amount <= 0 {
+			// Apple Pay and Samsung Pay tokens carry the amount; the mock uses a fixed one
+			amount = 100
+		}
+		o := g.newOrder(req.OrderNumber, amount)
+		o.description = req.Description
+		o.returnURL = req.ReturnURL
+		o.failURL = req.FailURL
+		o.clientID = req.ClientID
+		o.preAuth = req.PreAuth
+		o.email = req.Email
+		o.phone = req.Phone
+		o.ip = req.IP
+		if req.CurrencyCode != "" {
+			o.currency = req.CurrencyCode
+		}
+
+		resp := walletResponse{Data: map[string]string{"orderId": o.id}}
+		switch {
+		case kind == walletMirPay:
+			o.expires = o.created.Add(DefaultSessionTimeout)
+			resp.Data["formUrl"] = g.formURL(r, o)
+			resp.Data["deeplink"] = "mirpay://pay?" + url.Values{"mdOrder": {o.id}}.Encode()
+		case strings.Contains(strings.ToLower(req.PaymentToken), walletDeclineToken):
+			g.declinePayment(o, nil, walletPaymentWay(kind), actionCodeInsufficientFunds, "Insufficient funds")
+			resp.Error = &alfapay.WalletError{Code: actionCodeInsufficientFunds, Message: o.actionCodeDescription}
+			resp.Data = nil
+		default:
+			g.authorize(o, &card{pan: "4111111111111111", expiry: "203012"}, walletPaymentWay(kind))
+			if kind == walletYandexPay {
+				resp.Data["redirect"] = g.finishURL(o)
+			}
+		}
+		resp.Success = resp.Error == nil
+		resp.OrderStatus = o.response()
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func walletPaymentWay(kind walletKind) string {
+	switch kind {
+	case walletApplePay:
+		return "APPLE_PAY"
+	case walletGooglePay:
+		return "GOOGLE_PAY"
+	case walletSamsungPay:
+		return "SAMSUNG_PAY"
+	case walletMirPay, walletMirPayDirect:
+		return "MIR_PAY"
+	default:
+		return "YANDEX_PAY"
+	}
+}
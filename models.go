@@ -1,5 +1,10 @@
 package alfapay
 
+import (
+	"strings"
+	"time"
+)
+
 // OrderStatus represents the status of an order.
 type OrderStatus int
 
@@ -13,6 +18,11 @@ const (
 	OrderStatusDeclined         OrderStatus = 6 // Authorization declined
 )
 
+// IsTerminal returns true if the order is cancelled, refunded or declined.
+func (s OrderStatus) IsTerminal() bool {
+	return s == OrderStatusCancelled || s == OrderStatusRefunded || s == OrderStatusDeclined
+}
+
 // TaxType represents the tax type for fiscal operations.
 type TaxType int
 
@@ -43,9 +53,9 @@ const (
 
 // BaseResponse contains common response fields.
 type BaseResponse struct {
-	ErrorCode    string `json:"errorCode,omitempty"`
-	ErrorMessage string `json:"errorMessage,omitempty"`
-	UserMessage  string `json:"userMessage,omitempty"`
+	ErrorCode    FlexString `json:"errorCode,omitempty"`
+	ErrorMessage string     `json:"errorMessage,omitempty"`
+	UserMessage  string     `json:"userMessage,omitempty"`
 }
 
 // IsSuccess returns true if the response indicates success.
@@ -53,6 +63,14 @@ func (r BaseResponse) IsSuccess() bool {
 	return r.ErrorCode == "" || r.ErrorCode == "0"
 }
 
+// ErrorCodeOrderNotFound is the error code returned when no order matches the order ID or number.
+const ErrorCodeOrderNotFound = "6"
+
+// IsOrderNotFound returns true if the response reports that the order does not exist.
+func (r BaseResponse) IsOrderNotFound() bool {
+	return r.ErrorCode == ErrorCodeOrderNotFound
+}
+
 // RegisterOrderRequest represents a request to register a new order.
 type RegisterOrderRequest struct {
 	OrderNumber          string                 `json:"orderNumber"`
@@ -77,6 +95,13 @@ type RegisterOrderRequest struct {
 	AdditionalParameters map[string]string      `json:"additionalParameters,omitempty"`
 	DynamicCallbackURL   string                 `json:"dynamicCallbackUrl,omitempty"`
 	FeeInput             int64                  `json:"feeInput,omitempty"`
+	AutocompletionDate   string                 `json:"autocompletionDate,omitempty"` // Format: yyyy-MM-ddTHH:mm:ss
+	AutoReverseDate      string                 `json:"autoReverseDate,omitempty"`    // Format: yyyy-MM-ddTHH:mm:ss
+
+	// Time alternatives to the date strings above, formatted in Moscow time; used when the string is empty.
+	ExpirationTime     time.Time `json:"-"`
+	AutocompletionTime time.Time `json:"-"`
+	AutoReverseTime    time.Time `json:"-"`
 }
 
 // RegisterOrderResponse represents the response from order registration.
@@ -207,6 +232,16 @@ type GetOrderStatusExtendedResponse struct {
 	TransactionAttributes  []OrderAddendum     `json:"transactionAttributes,omitempty"`
 }
 
+// OrderID returns the gateway order ID, which is reported as the "mdOrder" attribute.
+func (r *GetOrderStatusExtendedResponse) OrderID() string {
+	for _, attr := range r.Attributes {
+		if attr.Name == "mdOrder" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
 // CardAuthInfo represents card authentication information.
 type CardAuthInfo struct {
 	MaskedPan       string `json:"maskedPan,omitempty"`
@@ -339,6 +374,8 @@ type ExtendBindingRequest struct {
 	BindingID string `json:"bindingId"`
 	NewExpiry string `json:"newExpiry"` // Format: YYYYMM
 	Language  string `json:"language,omitempty"`
+
+	NewExpiryTime time.Time `json:"-"` // Used when NewExpiry is empty; only the month is sent
 }
 
 // UnbindRequest represents a request to deactivate a binding.
@@ -364,6 +401,10 @@ type RecurrentPaymentRequest struct {
 	FeeInput             int64                  `json:"feeInput,omitempty"`
 	AutocompletionDate   string                 `json:"autocompletionDate,omitempty"`
 	AutoReverseDate      string                 `json:"autoReverseDate,omitempty"`
+
+	// Time alternatives to the date strings above, formatted in Moscow time; used when the string is empty.
+	AutocompletionTime time.Time `json:"-"`
+	AutoReverseTime    time.Time `json:"-"`
 }
 
 // RecurrentPaymentResponse represents the recurrent payment response.
@@ -585,6 +626,7 @@ type YandexPayResponse struct {
 	Success bool               `json:"success"`
 	Data    *YandexPayData     `json:"data,omitempty"`
 	Error   *YandexPayError    `json:"error,omitempty"`
+	OrderStatus *GetOrderStatusExtendedResponse `json:"orderStatus,omitempty"`
 }
 
 // YandexPayData represents successful Yandex Pay payment data.
@@ -602,6 +644,54 @@ type YandexPayError struct {
 	Message     string `json:"message,omitempty"`
 }
 
+// Unified wallet types
+
+// WalletPayment represents a wallet payment request common to all providers.
+// Fields not supported by a provider are ignored for that provider.
+type WalletPayment struct {
+	Merchant             string            `json:"merchant"`
+	OrderNumber          string            `json:"orderNumber"`
+	PaymentToken         string            `json:"paymentToken,omitempty"` // Not used by MIR Pay
+	Amount               int64             `json:"amount,omitempty"`
+	CurrencyCode         string            `json:"currencyCode,omitempty"`
+	IP                   string            `json:"ip,omitempty"`
+	ReturnURL            string            `json:"returnUrl,omitempty"`
+	FailURL              string            `json:"failUrl,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Language             string            `json:"language,omitempty"`
+	ClientID             string            `json:"clientId,omitempty"`
+	PreAuth              bool              `json:"preAuth,omitempty"`
+	Email                string            `json:"email,omitempty"`
+	Phone                string            `json:"phone,omitempty"`
+	PostAddress          string            `json:"postAddress,omitempty"`
+	AdditionalParameters map[string]string `json:"additionalParameters,omitempty"`
+	OrderBundle          *OrderBundle      `json:"orderBundle,omitempty"`
+	Direct               bool              `json:"-"` // Use paymentDirect.do (Samsung, MIR, Yandex); an error for Apple and Google Pay
+}
+
+// WalletResult represents a wallet payment response common to all providers.
+type WalletResult struct {
+	Provider    WalletProvider
+	Success     bool
+	OrderID     string
+	FormURL     string // MIR Pay
+	Deeplink    string // MIR Pay
+	Redirect    string // Yandex Pay
+	AcsURL      string // Yandex Pay
+	PaReq       string // Yandex Pay
+	Error       *WalletError
+	OrderStatus *GetOrderStatusExtendedResponse
+	// Raw holds the provider-specific response, e.g. *ApplePayPaymentResponse.
+	Raw interface{}
+}
+
+// WalletError represents a wallet payment error.
+type WalletError struct {
+	Code        int    `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
 // SBP QR Code types
 
 // SBPGetQRRequest represents a request to get SBP QR code.
@@ -662,6 +752,94 @@ type SBPBinding struct {
 	CreatedDate int64  `json:"createdDate,omitempty"`
 }
 
+// SBPSubscriptionStatus represents the status of an SBP subscription (binding consent).
+type SBPSubscriptionStatus string
+
+const (
+	SBPSubscriptionStatusCreated  SBPSubscriptionStatus = "CREATED"  // QR issued, waiting for the payer
+	SBPSubscriptionStatusActive   SBPSubscriptionStatus = "ACTIVE"   // Payer consented, binding created
+	SBPSubscriptionStatusRejected SBPSubscriptionStatus = "REJECTED" // Payer or bank declined
+	SBPSubscriptionStatusExpired  SBPSubscriptionStatus = "EXPIRED"  // QR expired without consent
+	SBPSubscriptionStatusDeleted  SBPSubscriptionStatus = "DELETED"  // Subscription revoked
+)
+
+// IsTerminal returns true if the subscription will not change status anymore.
+func (s SBPSubscriptionStatus) IsTerminal() bool {
+	return s != SBPSubscriptionStatusCreated && s != SBPSubscriptionStatusActive
+}
+
+// SBPSubscriptionQRRequest represents a request for a QR code asking the payer to consent to a subscription.
+type SBPSubscriptionQRRequest struct {
+	MDOrder             string `json:"mdOrder,omitempty"` // Pay the order and subscribe at once
+	ClientID            string `json:"clientId"`
+	SubscriptionPurpose string `json:"subscriptionPurpose,omitempty"`
+	RedirectURL         string `json:"redirectUrl,omitempty"`
+	QRHeight            int    `json:"qrHeight,omitempty"`
+	QRWidth             int    `json:"qrWidth,omitempty"`
+	QRFormat            string `json:"qrFormat,omitempty"` // image, matrix
+}
+
+// SBPSubscriptionQRResponse represents the SBP subscription QR code response.
+type SBPSubscriptionQRResponse struct {
+	BaseResponse
+	QRID    string `json:"qrId,omitempty"`
+	QRImage string `json:"qrImage,omitempty"` // Base64 encoded image
+	Payload string `json:"payload,omitempty"` // QR code payload
+	QRURL   string `json:"qrUrl,omitempty"`
+}
+
+// SBPSubscriptionStatusResponse represents the SBP subscription status response.
+type SBPSubscriptionStatusResponse struct {
+	BaseResponse
+	QRID      string                `json:"qrId,omitempty"`
+	Status    SBPSubscriptionStatus `json:"status,omitempty"`
+	BindingID string                `json:"bindingId,omitempty"`
+}
+
+// SBPRecurrentPaymentRequest represents a request to charge an SBP binding.
+type SBPRecurrentPaymentRequest struct {
+	OrderNumber        string `json:"orderNumber"`
+	BindingID          string `json:"bindingId"`
+	Amount             int64  `json:"amount"`
+	Currency           string `json:"currency,omitempty"`
+	Description        string `json:"description,omitempty"`
+	ClientID           string `json:"clientId,omitempty"`
+	Language           string `json:"language,omitempty"`
+	DynamicCallbackURL string `json:"dynamicCallbackUrl,omitempty"`
+}
+
+// SBPRecurrentPaymentResponse represents the SBP recurrent payment response.
+type SBPRecurrentPaymentResponse struct {
+	BaseResponse
+	OrderID     string      `json:"orderId,omitempty"`
+	OrderStatus OrderStatus `json:"orderStatus"`
+}
+
+// SBPBank represents an SBP participant bank.
+type SBPBank struct {
+	MemberID string `json:"memberId"` // NSPK member ID, used as BankID in B2B/B2C requests
+	BIC      string `json:"bic,omitempty"`
+	Name     string `json:"name"`
+	NameEn   string `json:"nameEn,omitempty"`
+	LogoURL  string `json:"logoUrl,omitempty"`
+}
+
+// SBPBanksResponse represents the SBP participant bank list response.
+type SBPBanksResponse struct {
+	BaseResponse
+	Banks []SBPBank `json:"banks,omitempty"`
+}
+
+// SBPRefundSummary represents the refund state of an SBP QR order.
+type SBPRefundSummary struct {
+	OrderID          string
+	DepositedAmount  int64
+	RefundedAmount   int64
+	RefundableAmount int64
+	AmountsKnown     bool // False if the gateway returned no paymentAmountInfo; the amounts are then zero
+	Refunds          []Refund
+}
+
 // InstantPaymentRequest represents a request for instant payment (register + pay).
 type InstantPaymentRequest struct {
 	OrderNumber          string                 `json:"orderNumber"`
@@ -747,6 +925,10 @@ type GetLastOrdersRequest struct {
 	TransactionStates string `json:"transactionStates,omitempty"`
 	Merchants     string `json:"merchants,omitempty"`
 	Language      string `json:"language,omitempty"`
+
+	// Time alternatives to FromDate and ToDate, formatted in Moscow time; used when the string is empty.
+	FromTime time.Time `json:"-"`
+	ToTime   time.Time `json:"-"`
 }
 
 // GetLastOrdersResponse represents the last orders response.
@@ -851,6 +1033,8 @@ type SBPB2CCheckPayoutResponse struct {
 	OrderID     string `json:"orderId,omitempty"`
 	OrderStatus string `json:"orderStatus,omitempty"`
 	Amount      int64  `json:"amount,omitempty"`
+	RecipientName string `json:"recipientName,omitempty"` // As registered at the recipient's bank, e.g. "Иван Иванович И."
+	BankName      string `json:"bankName,omitempty"`
 }
 
 // SBPB2CPayoutStatusResponse represents the B2C SBP payout status response.
@@ -862,6 +1046,27 @@ type SBPB2CPayoutStatusResponse struct {
 	StatusInfo  *SBPB2CStatusInfo `json:"statusInfo,omitempty"`
 }
 
+// PayoutStatus represents the status of a B2C SBP payout.
+type PayoutStatus string
+
+const (
+	PayoutStatusCreated    PayoutStatus = "CREATED"     // Payout registered
+	PayoutStatusInProgress PayoutStatus = "IN_PROGRESS" // Sent to NSPK, waiting for the recipient's bank
+	PayoutStatusSuccess    PayoutStatus = "SUCCESS"     // Funds credited to the recipient
+	PayoutStatusDeclined   PayoutStatus = "DECLINED"    // Rejected by NSPK or the recipient's bank
+	PayoutStatusError      PayoutStatus = "ERROR"       // Technical failure, funds were not sent
+)
+
+// IsTerminal returns true if the payout will not change status anymore.
+func (s PayoutStatus) IsTerminal() bool {
+	return s == PayoutStatusSuccess || s == PayoutStatusDeclined || s == PayoutStatusError
+}
+
+// PayoutStatus returns the typed payout status.
+func (r *SBPB2CPayoutStatusResponse) PayoutStatus() PayoutStatus {
+	return PayoutStatus(strings.ToUpper(r.OrderStatus))
+}
+
 // SBPB2CStatusInfo represents B2C SBP status information.
 type SBPB2CStatusInfo struct {
 	Status      string `json:"status,omitempty"`
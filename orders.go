@@ -2,10 +2,26 @@ package alfapay
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/url"
 	"strconv"
+	"time"
 )
 
+// ErrOrderAmountMismatch is returned by RegisterIdempotent when an order with the
+// same number already exists with a different amount.
+var ErrOrderAmountMismatch = errors.New("order already exists with a different amount")
+
+// ErrOrderClosed is returned by RegisterIdempotent when an order with the same number
+// already exists but was declined, cancelled or refunded and cannot be paid.
+var ErrOrderClosed = errors.New("order already exists and is closed")
+
+// idempotentLookupTimeout bounds the lookup of an existing order after an ambiguous registration.
+// The lookup runs even if the caller's context has expired, since that is often why registration failed.
+const idempotentLookupTimeout = 10 * time.Second
+
 // OrderService handles order registration and management operations.
 type OrderService struct {
 	client *Client
@@ -34,14 +50,14 @@ func (s *OrderService) Register(ctx context.Context, req *RegisterOrderRequest)
 	if req.ClientID != "" {
 		params.Set("clientId", req.ClientID)
 	}
-	if req.MerchantLogin != "" {
-		params.Set("merchantLogin", req.MerchantLogin)
+	if merchantLogin := s.client.merchantLoginOr(req.MerchantLogin); merchantLogin != "" {
+		params.Set("merchantLogin", merchantLogin)
 	}
 	if req.SessionTimeoutSecs > 0 {
 		params.Set("sessionTimeoutSecs", strconv.Itoa(req.SessionTimeoutSecs))
 	}
-	if req.ExpirationDate != "" {
-		params.Set("expirationDate", req.ExpirationDate)
+	if expirationDate := timeParam(req.ExpirationDate, req.ExpirationTime, FormatDateTime); expirationDate != "" {
+		params.Set("expirationDate", expirationDate)
 	}
 	if req.BindingID != "" {
 		params.Set("bindingId", req.BindingID)
@@ -100,14 +116,14 @@ func (s *OrderService) RegisterPreAuth(ctx context.Context, req *RegisterOrderRe
 	if req.ClientID != "" {
 		params.Set("clientId", req.ClientID)
 	}
-	if req.MerchantLogin != "" {
-		params.Set("merchantLogin", req.MerchantLogin)
+	if merchantLogin := s.client.merchantLoginOr(req.MerchantLogin); merchantLogin != "" {
+		params.Set("merchantLogin", merchantLogin)
 	}
 	if req.SessionTimeoutSecs > 0 {
 		params.Set("sessionTimeoutSecs", strconv.Itoa(req.SessionTimeoutSecs))
 	}
-	if req.ExpirationDate != "" {
-		params.Set("expirationDate", req.ExpirationDate)
+	if expirationDate := timeParam(req.ExpirationDate, req.ExpirationTime, FormatDateTime); expirationDate != "" {
+		params.Set("expirationDate", expirationDate)
 	}
 	if req.BindingID != "" {
 		params.Set("bindingId", req.BindingID)
@@ -133,6 +149,12 @@ func (s *OrderService) RegisterPreAuth(ctx context.Context, req *RegisterOrderRe
 	if req.TaxSystem != nil {
 		params.Set("taxSystem", strconv.Itoa(int(*req.TaxSystem)))
 	}
+	if autocompletionDate := timeParam(req.AutocompletionDate, req.AutocompletionTime, FormatDateTime); autocompletionDate != "" {
+		params.Set("autocompletionDate", autocompletionDate)
+	}
+	if autoReverseDate := timeParam(req.AutoReverseDate, req.AutoReverseTime, FormatDateTime); autoReverseDate != "" {
+		params.Set("autoReverseDate", autoReverseDate)
+	}
 
 	var resp RegisterOrderResponse
 	err := s.client.doFormRequest(ctx, "/rest/registerPreAuth.do", params, &resp)
@@ -142,6 +164,89 @@ func (s *OrderService) RegisterPreAuth(ctx context.Context, req *RegisterOrderRe
 	return &resp, nil
 }
 
+// RegisterIdempotent registers a single-stage order and is safe to retry with the same OrderNumber.
+// If the gateway reports the order number as already processed, or the transport fails
+// ambiguously, the existing order is looked up by number and returned when amounts match
+// and the order is still open; a declined, cancelled or refunded order yields ErrOrderClosed.
+// FormURL of a recovered order is only set when the client has WithPaymentPageURL configured.
+func (s *OrderService) RegisterIdempotent(ctx context.Context, req *RegisterOrderRequest) (*RegisterOrderResponse, error) {
+	return s.registerIdempotent(ctx, req, s.Register)
+}
+
+// RegisterPreAuthIdempotent is the two-stage counterpart of RegisterIdempotent.
+func (s *OrderService) RegisterPreAuthIdempotent(ctx context.Context, req *RegisterOrderRequest) (*RegisterOrderResponse, error) {
+	return s.registerIdempotent(ctx, req, s.RegisterPreAuth)
+}
+
+func (s *OrderService) registerIdempotent(
+	ctx context.Context,
+	req *RegisterOrderRequest,
+	register func(context.Context, *RegisterOrderRequest) (*RegisterOrderResponse, error),
+) (*RegisterOrderResponse, error) {
+	resp, err := register(ctx, req)
+	if err == nil && !isDuplicateOrderNumber(resp.BaseResponse) {
+		return resp, nil
+	}
+	if err != nil && !isAmbiguousFailure(err) {
+		return nil, err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotentLookupTimeout)
+	defer cancel()
+	existing, lookupErr := s.client.Status.GetByOrderNumber(lookupCtx, req.OrderNumber)
+	if lookupErr != nil || !existing.IsSuccess() {
+		// The order was not created (or cannot be confirmed): report the original outcome.
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if existing.Amount != req.Amount {
+		return nil, fmt.Errorf("%w: order %s has amount %d, requested %d",
+			ErrOrderAmountMismatch, req.OrderNumber, existing.Amount, req.Amount)
+	}
+
+	if existing.OrderStatus.IsTerminal() {
+		return nil, fmt.Errorf("%w: order %s has status %d", ErrOrderClosed, req.OrderNumber, existing.OrderStatus)
+	}
+
+	orderID := existing.OrderID()
+	if orderID == "" {
+		return nil, fmt.Errorf("order %s exists but its order ID was not returned", req.OrderNumber)
+	}
+
+	recovered := &RegisterOrderResponse{OrderID: orderID}
+	if s.client.paymentPageURL != "" {
+		recovered.FormURL = s.client.paymentPageURL + "?mdOrder=" + url.QueryEscape(orderID)
+	}
+	return recovered, nil
+}
+
+// isDuplicateOrderNumber reports whether the gateway may have rejected the order number as already used.
+// The message text varies, so only the code is checked; the lookup by number confirms the order exists.
+func isDuplicateOrderNumber(resp BaseResponse) bool {
+	return resp.ErrorCode == "1"
+}
+
+// isAmbiguousFailure reports whether a request may have reached the gateway despite failing:
+// a transport error, a timeout or a server error.
+func isAmbiguousFailure(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
 // Decline cancels an unpaid order.
 func (s *OrderService) Decline(ctx context.Context, req *DeclineRequest) (*BaseResponse, error) {
 	params := url.Values{}
@@ -151,8 +256,8 @@ func (s *OrderService) Decline(ctx context.Context, req *DeclineRequest) (*BaseR
 	if req.OrderNumber != "" {
 		params.Set("orderNumber", req.OrderNumber)
 	}
-	if req.MerchantLogin != "" {
-		params.Set("merchantLogin", req.MerchantLogin)
+	if merchantLogin := s.client.merchantLoginOr(req.MerchantLogin); merchantLogin != "" {
+		params.Set("merchantLogin", merchantLogin)
 	}
 	if req.Language != "" {
 		params.Set("language", req.Language)
@@ -160,6 +265,7 @@ func (s *OrderService) Decline(ctx context.Context, req *DeclineRequest) (*BaseR
 
 	var resp BaseResponse
 	err := s.client.doFormRequest(ctx, "/rest/decline.do", params, &resp)
+	s.client.statusCache.invalidate(req.OrderID, req.OrderNumber)
 	if err != nil {
 		return nil, err
 	}
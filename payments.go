@@ -36,6 +36,7 @@ func (s *PaymentService) Deposit(ctx context.Context, req *DepositRequest) (*Bas
 
 	var resp BaseResponse
 	err := s.client.doFormRequest(ctx, "/rest/deposit.do", params, &resp)
+	s.client.statusCache.invalidate(req.OrderID, "")
 	if err != nil {
 		return nil, err
 	}
@@ -59,6 +60,7 @@ func (s *PaymentService) Reverse(ctx context.Context, req *ReverseRequest) (*Bas
 
 	var resp BaseResponse
 	err := s.client.doFormRequest(ctx, "/rest/reverse.do", params, &resp)
+	s.client.statusCache.invalidate(req.OrderID, "")
 	if err != nil {
 		return nil, err
 	}
@@ -144,15 +146,21 @@ func (s *PaymentService) Recurrent(ctx context.Context, req *RecurrentPaymentReq
 		Password string `json:"password"`
 	}
 
-	reqBody := &recurrentReqWithAuth{
-		RecurrentPaymentRequest: req,
-		UserName:                s.client.userName,
-		Password:                s.client.password,
+	r := *req
+	r.AutocompletionDate = timeParam(r.AutocompletionDate, r.AutocompletionTime, FormatDateTime)
+	r.AutoReverseDate = timeParam(r.AutoReverseDate, r.AutoReverseTime, FormatDateTime)
+
+	reqBody := func(cred Credentials) interface{} {
+		return &recurrentReqWithAuth{
+			RecurrentPaymentRequest: &r,
+			UserName:                cred.UserName,
+			Password:                cred.Password,
+		}
 	}
 
 	var resp RecurrentPaymentResponse
-	// Use direct JSON request without query auth params
-	err := s.client.doJSONRequestNoAuth(ctx, "/recurrentPayment.do", reqBody, &resp)
+	// Credentials go in the JSON body instead of query params
+	err := s.client.doJSONRequestBodyAuth(ctx, "/recurrentPayment.do", reqBody, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,429 @@
+package alfapay
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPayoutBatchConcurrency is the default number of payouts sent in parallel.
+const DefaultPayoutBatchConcurrency = 4
+
+// payoutLineNotStarted is the error of lines skipped because the batch was cancelled.
+const payoutLineNotStarted = "not started"
+
+// PayoutBatchItem represents a single payout line of a batch.
+type PayoutBatchItem struct {
+	Line   int // Line number in the source, used in the report
+	Payout *SBPB2CPayoutRequest
+}
+
+// PayoutLineResult represents the outcome of a single payout line.
+type PayoutLineResult struct {
+	Line          int          `json:"line"`
+	OrderNumber   string       `json:"orderNumber"`
+	OrderID       string       `json:"orderId,omitempty"`
+	Amount        int64        `json:"amount"`
+	Status        PayoutStatus `json:"status,omitempty"`
+	RecipientName string       `json:"recipientName,omitempty"`
+	Error         string       `json:"error,omitempty"`
+	Performing    bool         `json:"performing,omitempty"`  // Perform was attempted, the outcome is not known yet
+	Interrupted   bool         `json:"interrupted,omitempty"` // Cancelled before the payout was performed
+	Resumed       bool         `json:"-"`                     // Taken from the checkpoint store, not sent again
+}
+
+// PayoutCheckpointStore persists payout line results so an interrupted batch can resume.
+// Implementations must be safe for concurrent use.
+type PayoutCheckpointStore interface {
+	// Load returns the saved results of a batch keyed by order number.
+	Load(ctx context.Context, batchID string) (map[string]PayoutLineResult, error)
+	// Save records the result of a payout line.
+	Save(ctx context.Context, batchID string, result PayoutLineResult) error
+}
+
+// PayoutBatchOptions configures PayoutService.SendBatch.
+type PayoutBatchOptions struct {
+	Concurrency   int                   // Default: DefaultPayoutBatchConcurrency
+	RatePerSecond float64               // Maximum payouts started per second, zero for no limit
+	Store         PayoutCheckpointStore // Default: in-memory store (no resume across restarts)
+
+	// ConfirmRecipient, PollInterval and Timeout are passed to Send for every line.
+	ConfirmRecipient func(item PayoutBatchItem, name string) bool
+	PollInterval     time.Duration
+	Timeout          time.Duration
+}
+
+// PayoutBatchReport represents the per-line results of a batch.
+type PayoutBatchReport struct {
+	BatchID   string
+	Results   []PayoutLineResult // Sorted by line
+	Succeeded int
+	Failed    int // Declined, or rejected before the payout was performed
+	Pending   int // Not started or interrupted, outcome unknown or still in progress, safe to resume
+}
+
+// SendBatch sends a batch of payouts with bounded concurrency and an optional rate limit.
+// Results are checkpointed to the store; running the same batch ID again skips lines that
+// reached a terminal status and re-checks the rest by order number, so nothing is paid twice.
+// Cancelling the context stops starting new lines; lines in flight finish their current step.
+func (s *PayoutService) SendBatch(ctx context.Context, batchID string, items []PayoutBatchItem, opts PayoutBatchOptions) (*PayoutBatchReport, error) {
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryPayoutCheckpointStore()
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultPayoutBatchConcurrency
+	}
+
+	seen := make(map[string]int, len(items))
+	for _, item := range items {
+		if item.Payout == nil || item.Payout.OrderNumber == "" {
+			return nil, fmt.Errorf("payout batch line %d: order number is required", item.Line)
+		}
+		if line, ok := seen[item.Payout.OrderNumber]; ok {
+			return nil, fmt.Errorf("payout batch line %d: duplicate order number %s (line %d)",
+				item.Line, item.Payout.OrderNumber, line)
+		}
+		seen[item.Payout.OrderNumber] = item.Line
+	}
+
+	saved, err := store.Load(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payout checkpoint: %w", err)
+	}
+
+	var throttle <-chan time.Time
+	if opts.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RatePerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	results := make([]PayoutLineResult, len(items))
+	for i, item := range items {
+		results[i] = PayoutLineResult{
+			Line:        item.Line,
+			OrderNumber: item.Payout.OrderNumber,
+			Amount:      item.Payout.Amount,
+			Error:       payoutLineNotStarted,
+		}
+	}
+	jobs := make(chan int)
+
+	var (
+		wg      sync.WaitGroup
+		saveMu  sync.Mutex
+		saveErr error
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.sendLine(ctx, store, batchID, items[i], opts)
+				// The outcome is recorded even if the batch was cancelled meanwhile.
+				if err := store.Save(context.WithoutCancel(ctx), batchID, results[i]); err != nil {
+					saveMu.Lock()
+					saveErr = errors.Join(saveErr, err)
+					saveMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for i, item := range items {
+		if prev, ok := saved[item.Payout.OrderNumber]; ok && prev.Status.IsTerminal() {
+			prev.Line = item.Line
+			prev.Resumed = true
+			results[i] = prev
+			continue
+		}
+
+		if throttle != nil && dispatched > 0 {
+			select {
+			case <-ctx.Done():
+				break dispatch
+			case <-throttle:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+			dispatched++
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := newPayoutBatchReport(batchID, results)
+	if saveErr != nil {
+		return report, fmt.Errorf("failed to save payout checkpoint: %w", saveErr)
+	}
+	return report, ctx.Err()
+}
+
+func (s *PayoutService) sendLine(ctx context.Context, store PayoutCheckpointStore, batchID string, item PayoutBatchItem, opts PayoutBatchOptions) PayoutLineResult {
+	req := &PayoutRequest{
+		Payout:       item.Payout,
+		PollInterval: opts.PollInterval,
+		Timeout:      opts.Timeout,
+	}
+	if opts.ConfirmRecipient != nil {
+		req.ConfirmRecipient = func(name string) bool { return opts.ConfirmRecipient(item, name) }
+	}
+
+	line := PayoutLineResult{
+		Line:        item.Line,
+		OrderNumber: item.Payout.OrderNumber,
+		Amount:      item.Payout.Amount,
+	}
+	req.BeforePerform = func(ctx context.Context) error {
+		performing := line
+		performing.Performing = true
+		return store.Save(context.WithoutCancel(ctx), batchID, performing)
+	}
+
+	result, err := s.Send(ctx, req)
+	if result != nil {
+		line.OrderID = result.OrderID
+		line.Amount = result.Amount
+		line.Status = result.Status
+		line.RecipientName = result.RecipientName
+	}
+	if err != nil {
+		line.Error = err.Error()
+		line.Performing = errors.Is(err, ErrPayoutOutcomeUnknown)
+		line.Interrupted = !line.Performing && (ctx.Err() != nil ||
+			errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+	}
+	return line
+}
+
+func newPayoutBatchReport(batchID string, results []PayoutLineResult) *PayoutBatchReport {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+
+	report := &PayoutBatchReport{BatchID: batchID, Results: results}
+	for _, r := range results {
+		switch {
+		case r.Status == PayoutStatusSuccess:
+			report.Succeeded++
+		case r.Status.IsTerminal():
+			report.Failed++
+		case r.OrderID == "" && !r.Performing && !r.Interrupted && r.Error != payoutLineNotStarted:
+			// Rejected before the payout was performed: nothing was paid.
+			report.Failed++
+		default:
+			report.Pending++
+		}
+	}
+	return report
+}
+
+// WriteCSV writes the per-line report as CSV.
+func (r *PayoutBatchReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"line", "order_number", "order_id", "amount", "status", "recipient_name", "error"}); err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		record := []string{
+			strconv.Itoa(res.Line),
+			res.OrderNumber,
+			res.OrderID,
+			strconv.FormatInt(res.Amount, 10),
+			string(res.Status),
+			res.RecipientName,
+			res.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParsePayoutCSV reads payout lines from CSV with a header row.
+// Recognized columns: order_number, amount (kopecks), phone, bank_id, first_name,
+// middle_name, last_name, purpose, currency. order_number, amount, phone and bank_id are required.
+func ParsePayoutCSV(r io.Reader) ([]PayoutBatchItem, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payout csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"order_number", "amount", "phone", "bank_id"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("payout csv is missing column %q", required)
+		}
+	}
+
+	var items []PayoutBatchItem
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("payout csv line %d: %w", line, err)
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		amount, err := strconv.ParseInt(get("amount"), 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("payout csv line %d: invalid amount %q", line, get("amount"))
+		}
+
+		payout := &SBPB2CPayoutRequest{
+			OrderNumber: get("order_number"),
+			Amount:      amount,
+			Currency:    get("currency"),
+			Purpose:     get("purpose"),
+			RecipientParams: &SBPB2CRecipientParams{
+				BankID: get("bank_id"),
+				Phone:  get("phone"),
+			},
+		}
+		if first, last := get("first_name"), get("last_name"); first != "" || last != "" {
+			payout.RecipientParams.Name = &SBPB2CRecipientName{
+				FirstName:  first,
+				MiddleName: get("middle_name"),
+				LastName:   last,
+			}
+		}
+
+		items = append(items, PayoutBatchItem{Line: line, Payout: payout})
+	}
+	return items, nil
+}
+
+// MemoryPayoutCheckpointStore is an in-memory PayoutCheckpointStore.
+type MemoryPayoutCheckpointStore struct {
+	mu      sync.Mutex
+	batches map[string]map[string]PayoutLineResult
+}
+
+// NewMemoryPayoutCheckpointStore creates a new in-memory checkpoint store.
+func NewMemoryPayoutCheckpointStore() *MemoryPayoutCheckpointStore {
+	return &MemoryPayoutCheckpointStore{batches: make(map[string]map[string]PayoutLineResult)}
+}
+
+// Load returns the saved results of a batch.
+func (s *MemoryPayoutCheckpointStore) Load(_ context.Context, batchID string) (map[string]PayoutLineResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := make(map[string]PayoutLineResult, len(s.batches[batchID]))
+	for k, v := range s.batches[batchID] {
+		saved[k] = v
+	}
+	return saved, nil
+}
+
+// Save records the result of a payout line.
+func (s *MemoryPayoutCheckpointStore) Save(_ context.Context, batchID string, result PayoutLineResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.batches[batchID] == nil {
+		s.batches[batchID] = make(map[string]PayoutLineResult)
+	}
+	s.batches[batchID][result.OrderNumber] = result
+	return nil
+}
+
+// FilePayoutCheckpointStore is a PayoutCheckpointStore that appends results
+// to a JSON Lines file per batch in a directory.
+type FilePayoutCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFilePayoutCheckpointStore creates a file checkpoint store in the given directory.
+func NewFilePayoutCheckpointStore(dir string) (*FilePayoutCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &FilePayoutCheckpointStore{dir: dir}, nil
+}
+
+func (s *FilePayoutCheckpointStore) path(batchID string) string {
+	return filepath.Join(s.dir, filepath.Base(batchID)+".jsonl")
+}
+
+// Load returns the saved results of a batch; later lines override earlier ones.
+func (s *FilePayoutCheckpointStore) Load(_ context.Context, batchID string) (map[string]PayoutLineResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]PayoutLineResult)
+	f, err := os.Open(s.path(batchID))
+	if errors.Is(err, os.ErrNotExist) {
+		return saved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var result PayoutLineResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			// A torn last line from a crash is ignored; the line is re-checked on resume.
+			continue
+		}
+		saved[result.OrderNumber] = result
+	}
+	return saved, scanner.Err()
+}
+
+// Save appends the result of a payout line and syncs it to disk.
+func (s *FilePayoutCheckpointStore) Save(_ context.Context, batchID string, result PayoutLineResult) error {
+	data, err := json.Marshal(&result)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path(batchID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,202 @@
+package alfapay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPayoutPollInterval is the default interval between payout status checks.
+	DefaultPayoutPollInterval = 2 * time.Second
+	// DefaultPayoutTimeout is the default time to wait for a payout to reach a terminal status.
+	DefaultPayoutTimeout = 2 * time.Minute
+)
+
+// ErrPayoutRecipientRejected is returned when the recipient name was not confirmed.
+var ErrPayoutRecipientRejected = errors.New("payout recipient was not confirmed")
+
+// ErrPayoutOutcomeUnknown is returned when the perform request failed without a gateway response,
+// so the payout may or may not have been performed. Retry Send with the same OrderNumber to find out.
+var ErrPayoutOutcomeUnknown = errors.New("payout outcome is unknown")
+
+// PayoutService orchestrates B2C SBP payouts: recipient check, idempotent payout and status tracking.
+type PayoutService struct {
+	client *Client
+}
+
+// PayoutRequest represents a payout to be sent with PayoutService.Send.
+type PayoutRequest struct {
+	Payout *SBPB2CPayoutRequest
+
+	// ConfirmRecipient is called with the recipient name returned by the check step.
+	// If nil, the name is compared with Payout.RecipientParams.Name, which must then be set.
+	ConfirmRecipient func(name string) bool
+
+	// BeforePerform is called right before the payout is performed, e.g. to checkpoint the attempt.
+	// If it returns an error, the payout is not performed.
+	BeforePerform func(ctx context.Context) error
+
+	PollInterval time.Duration // Default: DefaultPayoutPollInterval
+	Timeout      time.Duration // Default: DefaultPayoutTimeout
+}
+
+// PayoutResult represents the consolidated outcome of a payout.
+type PayoutResult struct {
+	OrderID       string
+	OrderNumber   string
+	Amount        int64
+	Status        PayoutStatus
+	StatusInfo    *SBPB2CStatusInfo
+	RecipientName string
+	Existing      bool // The payout had already been performed for this OrderNumber
+}
+
+// Send performs a payout and waits for a terminal status.
+// It is safe to retry with the same OrderNumber: an existing payout is tracked instead of performed again.
+// If the status is still not terminal when the timeout expires, the result is returned with the context error.
+// If the perform request fails without a gateway response, the result is returned with ErrPayoutOutcomeUnknown.
+func (s *PayoutService) Send(ctx context.Context, req *PayoutRequest) (*PayoutResult, error) {
+	payout := req.Payout
+	result := &PayoutResult{OrderNumber: payout.OrderNumber, Amount: payout.Amount}
+
+	orderID, err := s.findExisting(ctx, payout)
+	if err != nil {
+		return nil, err
+	}
+
+	if orderID != "" {
+		result.Existing = true
+	} else {
+		check, err := s.client.SBP.B2CPreCheckPayout(ctx, payout)
+		if err != nil {
+			return nil, err
+		}
+		if !check.IsSuccess() {
+			return nil, fmt.Errorf("payout check failed: %s", check.ErrorMessage)
+		}
+		result.RecipientName = check.RecipientName
+
+		confirm := req.ConfirmRecipient
+		if confirm == nil {
+			confirm = func(name string) bool { return recipientNameMatches(payout.RecipientParams, name) }
+		}
+		if !confirm(check.RecipientName) {
+			return nil, fmt.Errorf("%w: %q", ErrPayoutRecipientRejected, check.RecipientName)
+		}
+
+		if req.BeforePerform != nil {
+			if err := req.BeforePerform(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := s.client.SBP.B2CPerformPayout(ctx, payout)
+		if err != nil {
+			return result, fmt.Errorf("%w: %w", ErrPayoutOutcomeUnknown, err)
+		}
+		if !resp.IsSuccess() {
+			return nil, fmt.Errorf("payout failed: %s", resp.ErrorMessage)
+		}
+		orderID = resp.OrderID
+	}
+	result.OrderID = orderID
+
+	return s.wait(ctx, req, result)
+}
+
+// findExisting returns the order ID of a payout already registered with the same order number.
+// Only an explicit "order not found" response means there is no payout; any other failure is
+// returned, since performing the payout without knowing could pay the recipient twice.
+func (s *PayoutService) findExisting(ctx context.Context, payout *SBPB2CPayoutRequest) (string, error) {
+	existing, err := s.client.Status.GetByOrderNumber(ctx, payout.OrderNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up payout %s: %w", payout.OrderNumber, err)
+	}
+	if existing.IsOrderNotFound() {
+		return "", nil
+	}
+	if !existing.IsSuccess() {
+		return "", fmt.Errorf("failed to look up payout %s: %s", payout.OrderNumber, existing.ErrorMessage)
+	}
+	if existing.Amount != 0 && existing.Amount != payout.Amount {
+		return "", fmt.Errorf("%w: payout %s has amount %d, requested %d",
+			ErrOrderAmountMismatch, payout.OrderNumber, existing.Amount, payout.Amount)
+	}
+	orderID := existing.OrderID()
+	if orderID == "" {
+		return "", fmt.Errorf("payout %s exists but its order ID was not returned", payout.OrderNumber)
+	}
+	return orderID, nil
+}
+
+// wait polls the payout status until it is terminal or the timeout expires.
+func (s *PayoutService) wait(ctx context.Context, req *PayoutRequest, result *PayoutResult) (*PayoutResult, error) {
+	interval := req.PollInterval
+	if interval <= 0 {
+		interval = DefaultPayoutPollInterval
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPayoutTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		status, err := s.client.SBP.B2CGetPayoutStatus(ctx, result.OrderID)
+		switch {
+		case err != nil:
+			lastErr = err
+		case !status.IsSuccess():
+			lastErr = fmt.Errorf("failed to get payout status: %s", status.ErrorMessage)
+		default:
+			lastErr = nil
+			result.Status = status.PayoutStatus()
+			result.StatusInfo = status.StatusInfo
+			if status.Amount > 0 {
+				result.Amount = status.Amount
+			}
+			if result.Status.IsTerminal() {
+				return result, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return result, fmt.Errorf("payout %s did not complete: %w (last status check: %w)", result.OrderID, ctx.Err(), lastErr)
+			}
+			return result, fmt.Errorf("payout %s did not complete: %w", result.OrderID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// recipientNameMatches compares the expected recipient name with the masked name returned by the bank.
+// The bank usually returns the first name, patronymic and the last name initial ("Иван Иванович И.").
+func recipientNameMatches(params *SBPB2CRecipientParams, name string) bool {
+	if params == nil || params.Name == nil {
+		return false
+	}
+
+	got := strings.Fields(strings.ToLower(strings.ReplaceAll(name, ".", " ")))
+	if len(got) == 0 || !strings.EqualFold(got[0], params.Name.FirstName) {
+		return false
+	}
+
+	if last := []rune(strings.ToLower(params.Name.LastName)); len(last) > 0 {
+		initial := []rune(got[len(got)-1])
+		if len(got) < 2 || len(initial) == 0 || initial[0] != last[0] {
+			return false
+		}
+	}
+	return true
+}
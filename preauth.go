@@ -0,0 +1,254 @@
+package alfapay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPreAuthMaxAge is the default age after which a held pre-authorization is reversed.
+const DefaultPreAuthMaxAge = 5 * 24 * time.Hour
+
+// PreAuthHold represents funds held by a pre-authorized (two-stage) order.
+type PreAuthHold struct {
+	OrderID      string
+	OrderNumber  string
+	Amount       int64
+	AuthorizedAt time.Time
+}
+
+// PreAuthManager tracks pre-authorized orders and completes or releases them
+// before the hold expires.
+type PreAuthManager struct {
+	client *Client
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	holds map[string]*PreAuthHold
+}
+
+// PreAuthOption is a function that configures the pre-auth manager.
+type PreAuthOption func(*PreAuthManager)
+
+// WithPreAuthMaxAge sets the age after which holds are auto-reversed.
+func WithPreAuthMaxAge(maxAge time.Duration) PreAuthOption {
+	return func(m *PreAuthManager) {
+		m.maxAge = maxAge
+	}
+}
+
+// WithPreAuthClock sets the clock used to compute hold age.
+func WithPreAuthClock(now func() time.Time) PreAuthOption {
+	return func(m *PreAuthManager) {
+		m.now = now
+	}
+}
+
+// NewPreAuthManager creates a new pre-authorization lifecycle manager.
+func NewPreAuthManager(client *Client, opts ...PreAuthOption) *PreAuthManager {
+	m := &PreAuthManager{
+		client: client,
+		maxAge: DefaultPreAuthMaxAge,
+		now:    time.Now,
+		holds:  make(map[string]*PreAuthHold),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Track starts tracking a held order.
+// A zero AuthorizedAt is replaced with the current time.
+func (m *PreAuthManager) Track(hold PreAuthHold) {
+	if hold.AuthorizedAt.IsZero() {
+		hold.AuthorizedAt = m.now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holds[hold.OrderID] = &hold
+}
+
+// Untrack stops tracking an order without touching it at the gateway.
+func (m *PreAuthManager) Untrack(orderID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.holds, orderID)
+}
+
+// Get returns the tracked hold for an order.
+func (m *PreAuthManager) Get(orderID string) (PreAuthHold, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hold, ok := m.holds[orderID]
+	if !ok {
+		return PreAuthHold{}, false
+	}
+	return *hold, true
+}
+
+// Holds returns all tracked holds, oldest first.
+func (m *PreAuthManager) Holds() []PreAuthHold {
+	m.mu.Lock()
+	holds := make([]PreAuthHold, 0, len(m.holds))
+	for _, hold := range m.holds {
+		holds = append(holds, *hold)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(holds, func(i, j int) bool {
+		return holds[i].AuthorizedAt.Before(holds[j].AuthorizedAt)
+	})
+	return holds
+}
+
+// Sync refreshes a hold from the gateway using the extended order status.
+// Orders that are no longer pre-authorized are untracked and nil is returned.
+func (m *PreAuthManager) Sync(ctx context.Context, orderID string) (*PreAuthHold, error) {
+	status, err := m.client.Status.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsSuccess() {
+		return nil, fmt.Errorf("failed to get status of order %s: %s", orderID, status.ErrorMessage)
+	}
+
+	if status.OrderStatus != OrderStatusPreAuthorized {
+		m.Untrack(orderID)
+		return nil, nil
+	}
+
+	hold := PreAuthHold{
+		OrderID:     orderID,
+		OrderNumber: status.OrderNumber,
+		Amount:      status.Amount,
+	}
+	if status.PaymentAmountInfo != nil && status.PaymentAmountInfo.ApprovedAmount > 0 {
+		hold.Amount = status.PaymentAmountInfo.ApprovedAmount
+	}
+	hold.AuthorizedAt = status.AuthorizedAt()
+	if hold.AuthorizedAt.IsZero() {
+		// Keep the known authorization time so the hold does not look younger than it is
+		if prev, ok := m.Get(orderID); ok {
+			hold.AuthorizedAt = prev.AuthorizedAt
+		}
+	}
+
+	m.Track(hold)
+	return &hold, nil
+}
+
+// SyncAll refreshes every tracked hold from the gateway.
+func (m *PreAuthManager) SyncAll(ctx context.Context) error {
+	var errs []error
+	for _, hold := range m.Holds() {
+		if _, err := m.Sync(ctx, hold.OrderID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Capture completes a held order.
+// An amount of zero captures the full held amount; a smaller amount performs a partial capture.
+func (m *PreAuthManager) Capture(ctx context.Context, orderID string, amount int64) (*BaseResponse, error) {
+	hold, ok := m.Get(orderID)
+	if !ok {
+		return nil, fmt.Errorf("order %s is not tracked", orderID)
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("invalid capture amount %d", amount)
+	}
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	if amount > hold.Amount {
+		return nil, fmt.Errorf("capture amount %d exceeds held amount %d", amount, hold.Amount)
+	}
+
+	resp, err := m.client.Payments.Deposit(ctx, &DepositRequest{
+		OrderID: orderID,
+		Amount:  amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsSuccess() {
+		m.Untrack(orderID)
+	}
+	return resp, nil
+}
+
+// Release reverses a held order, returning the funds to the customer.
+func (m *PreAuthManager) Release(ctx context.Context, orderID string) (*BaseResponse, error) {
+	resp, err := m.client.Payments.Reverse(ctx, &ReverseRequest{OrderID: orderID})
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsSuccess() {
+		m.Untrack(orderID)
+	}
+	return resp, nil
+}
+
+// Expiring returns holds that will exceed the maximum age within the given duration.
+func (m *PreAuthManager) Expiring(within time.Duration) []PreAuthHold {
+	deadline := m.now().Add(within)
+
+	var expiring []PreAuthHold
+	for _, hold := range m.Holds() {
+		if !hold.AuthorizedAt.Add(m.maxAge).After(deadline) {
+			expiring = append(expiring, hold)
+		}
+	}
+	return expiring
+}
+
+// ReverseExpired reverses all holds that exceeded the maximum age.
+// It returns the IDs of reversed orders.
+func (m *PreAuthManager) ReverseExpired(ctx context.Context) ([]string, error) {
+	var (
+		reversed []string
+		errs     []error
+	)
+	for _, hold := range m.Expiring(0) {
+		resp, err := m.Release(ctx, hold.OrderID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !resp.IsSuccess() {
+			errs = append(errs, fmt.Errorf("failed to reverse order %s: %s", hold.OrderID, resp.ErrorMessage))
+			continue
+		}
+		reversed = append(reversed, hold.OrderID)
+	}
+	return reversed, errors.Join(errs...)
+}
+
+// Run periodically reverses expired holds until the context is cancelled.
+// Holds that fail to reverse stay tracked and are retried on the next tick.
+func (m *PreAuthManager) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid pre-auth check interval %v", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, _ = m.ReverseExpired(ctx)
+		}
+	}
+}
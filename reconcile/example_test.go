@@ -0,0 +1,106 @@
+package reconcile_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+	"github.com/KlimGrishanov/alfapay/reconcile"
+)
+
+func Example() {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+	client := alfapay.NewClient("test-api", "test", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	// Gateway side: orders paid, refunded and charged back through the mock's admin API
+	admin := func(number, action, body string) {
+		resp, err := http.Post(srv.URL+"/admin/orders/"+number+"/"+action, "application/json", strings.NewReader(body))
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	for number, amount := range map[string]int64{"A-1": 1000, "A-2": 2000, "A-3": 3000, "A-4": 4000, "A-6": 6000, "A-7": 7500} {
+		_, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+			OrderNumber: number, Amount: amount, ReturnURL: "https://shop.example.com/return",
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if number != "A-6" {
+			admin(number, "pay", "")
+		}
+	}
+	a2, _ := client.Status.GetByOrderNumber(ctx, "A-2")
+	if _, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: a2.OrderID(), Amount: 500}); err != nil {
+		log.Fatal(err)
+	}
+	admin("A-3", "chargeback", "")
+
+	// Ledger side: A-2's refund and A-3's chargeback were never recorded, A-4 is missing,
+	// A-5 never reached the gateway and A-7 was booked with the wrong amount.
+	// A-6 was never paid, so its absence from the ledger is not reported.
+	ledger := reconcile.LedgerFunc(func(ctx context.Context, from, to time.Time) ([]reconcile.Record, error) {
+		paid := alfapay.OrderStatusFullyAuthorized
+		return []reconcile.Record{
+			{OrderNumber: "A-1", Amount: 1000, Status: paid},
+			{OrderNumber: "A-2", Amount: 2000, Status: paid},
+			{OrderNumber: "A-3", Amount: 3000, Status: paid},
+			{OrderNumber: "A-5", Amount: 5000, Status: paid},
+			{OrderNumber: "A-7", Amount: 7000, Status: paid},
+		}, nil
+	})
+
+	now := time.Now()
+	report, err := reconcile.New(client, ledger).Run(ctx, now.Add(-time.Hour), now)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("gateway %d, ledger %d, matched %d\n", report.GatewayOrders, report.LedgerRecords, report.Matched)
+	for _, m := range report.Mismatches {
+		fmt.Printf("%s %s ledger=%q gateway=%q\n", m.OrderNumber, m.Kind, m.Ledger, m.Gateway)
+	}
+	// Output:
+	// gateway 6, ledger 5, matched 1
+	// A-2 refunded_amount ledger="0" gateway="500"
+	// A-3 chargeback ledger="false" gateway="true"
+	// A-4 missing_in_ledger ledger="" gateway="4000"
+	// A-5 missing_on_gateway ledger="5000" gateway=""
+	// A-7 amount ledger="7000" gateway="7500"
+}
+
+func ExampleReport_WriteCSV() {
+	client := alfapay.NewClient("your-username", "your-password")
+	ctx := context.Background()
+
+	ledger := reconcile.LedgerFunc(func(ctx context.Context, from, to time.Time) ([]reconcile.Record, error) {
+		// Query the accounting database for orders created between from and to
+		return nil, nil
+	})
+
+	// Yesterday in Moscow time, including pre-authorized orders the ledger books as paid
+	now := time.Now().In(alfapay.MoscowTime)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, alfapay.MoscowTime)
+	r := reconcile.New(client, ledger, reconcile.WithStatusMatcher(func(ledger, gateway alfapay.OrderStatus) bool {
+		return ledger == gateway || ledger == alfapay.OrderStatusFullyAuthorized && gateway == alfapay.OrderStatusPreAuthorized
+	}))
+	report, err := r.Run(ctx, today.AddDate(0, 0, -1), today.Add(-time.Second))
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+	if !report.OK() {
+		if err := report.WriteCSV(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package reconcile compares the orders the gateway holds for a period with the merchant's
+// own ledger and reports every difference: orders missing on either side, amount and refund
+// differences, status drift and chargebacks the ledger does not know about.
+//
+//	r := reconcile.New(client, ledger)
+//	report, err := r.Run(ctx, from, to)
+//	report.WriteCSV(os.Stdout)
+package reconcile
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// DefaultPageSize is the number of orders requested per GetLastOrders page.
+const DefaultPageSize = 200
+
+// Record is an order as the merchant's ledger sees it.
+type Record struct {
+	OrderNumber    string              `json:"orderNumber"`
+	OrderID        string              `json:"orderId,omitempty"` // Gateway order ID, if the ledger stores it
+	Amount         int64               `json:"amount"`            // Order amount in kopecks
+	Status         alfapay.OrderStatus `json:"status"`
+	RefundedAmount int64               `json:"refundedAmount"` // Total refunded in kopecks
+	Chargeback     bool                `json:"chargeback"`     // The ledger knows about a chargeback
+}
+
+// LedgerSource supplies the ledger records to reconcile.
+type LedgerSource interface {
+	// Records returns the records of orders created between from and to.
+	Records(ctx context.Context, from, to time.Time) ([]Record, error)
+}
+
+// LedgerFunc adapts a function to LedgerSource.
+type LedgerFunc func(ctx context.Context, from, to time.Time) ([]Record, error)
+
+// Records calls f.
+func (f LedgerFunc) Records(ctx context.Context, from, to time.Time) ([]Record, error) {
+	return f(ctx, from, to)
+}
+
+// Kind is the kind of a mismatch.
+type Kind string
+
+const (
+	MissingInLedger    Kind = "missing_in_ledger"   // Order on the gateway only
+	MissingOnGateway   Kind = "missing_on_gateway"  // Ledger record the gateway does not know
+	DuplicateInLedger  Kind = "duplicate_in_ledger" // Several ledger records with one order number
+	AmountMismatch     Kind = "amount"              // Order amounts differ
+	RefundMismatch     Kind = "refunded_amount"     // Refunded totals differ
+	StatusMismatch     Kind = "status"              // Order statuses differ
+	ChargebackMismatch Kind = "chargeback"          // Chargeback flags differ
+)
+
+// Mismatch is a difference between the ledger and the gateway for one order.
+type Mismatch struct {
+	Kind        Kind   `json:"kind"`
+	OrderNumber string `json:"orderNumber"`
+	OrderID     string `json:"orderId,omitempty"`
+	Ledger      string `json:"ledger,omitempty"`  // Ledger value, e.g. the amount or status
+	Gateway     string `json:"gateway,omitempty"` // Gateway value
+	Detail      string `json:"detail,omitempty"`
+
+	Record *Record                                 `json:"-"` // Nil for MissingInLedger
+	Order  *alfapay.GetOrderStatusExtendedResponse `json:"-"` // Nil for MissingOnGateway
+}
+
+// Report is the result of a reconciliation run.
+type Report struct {
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	GatewayOrders int          `json:"gatewayOrders"`
+	LedgerRecords int          `json:"ledgerRecords"`
+	Matched       int          `json:"matched"` // Orders present on both sides without differences
+	Counts        map[Kind]int `json:"counts"`
+	Mismatches    []Mismatch   `json:"mismatches"` // Sorted by order number
+}
+
+// OK reports whether the ledger and the gateway agree.
+func (r *Report) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// WriteCSV writes the mismatches as CSV.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"kind", "order_number", "order_id", "ledger", "gateway", "detail"}); err != nil {
+		return err
+	}
+	for _, m := range r.Mismatches {
+		if err := cw.Write([]string{string(m.Kind), m.OrderNumber, m.OrderID, m.Ledger, m.Gateway, m.Detail}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Reconciler compares gateway orders with a ledger.
+type Reconciler struct {
+	client        *alfapay.Client
+	ledger        LedgerSource
+	merchants     string
+	pageSize      int
+	includeUnpaid bool
+	statusMatch   func(ledger, gateway alfapay.OrderStatus) bool
+}
+
+// Option configures a Reconciler.
+type Option func(*Reconciler)
+
+// WithMerchants limits the gateway orders to the given sub-merchant logins.
+func WithMerchants(logins string) Option {
+	return func(r *Reconciler) {
+		r.merchants = logins
+	}
+}
+
+// WithPageSize sets the number of orders requested per page (default DefaultPageSize).
+func WithPageSize(size int) Option {
+	return func(r *Reconciler) {
+		r.pageSize = size
+	}
+}
+
+// WithUnpaidOrders also reports gateway orders that were never paid (registered, awaiting
+// 3-D Secure or declined) and are missing from the ledger. They are skipped by default
+// because abandoned checkouts often never reach the ledger.
+func WithUnpaidOrders() Option {
+	return func(r *Reconciler) {
+		r.includeUnpaid = true
+	}
+}
+
+// WithStatusMatcher replaces the status comparison, e.g. to accept a ledger that does not
+// distinguish pre-authorized from paid orders. The default requires equal statuses.
+func WithStatusMatcher(match func(ledger, gateway alfapay.OrderStatus) bool) Option {
+	return func(r *Reconciler) {
+		r.statusMatch = match
+	}
+}
+
+// New creates a Reconciler.
+func New(client *alfapay.Client, ledger LedgerSource, opts ...Option) *Reconciler {
+	r := &Reconciler{
+		client:   client,
+		ledger:   ledger,
+		pageSize: DefaultPageSize,
+		statusMatch: func(ledger, gateway alfapay.OrderStatus) bool {
+			return ledger == gateway
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run reconciles the orders created between from and to.
+// Ledger records not returned by GetLastOrders for the period are looked up one by one, so
+// orders near the period boundaries are compared rather than reported as missing.
+func (r *Reconciler) Run(ctx context.Context, from, to time.Time) (*Report, error) {
+	records, err := r.ledger.Records(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	var orders []*alfapay.GetOrderStatusExtendedResponse
+	err = r.client.Status.EachLastOrder(ctx, &alfapay.GetLastOrdersRequest{
+		FromTime:  from,
+		ToTime:    to,
+		Size:      r.pageSize,
+		Merchants: r.merchants,
+	}, func(order *alfapay.GetOrderStatusExtendedResponse) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		From:          from,
+		To:            to,
+		GatewayOrders: len(orders),
+		LedgerRecords: len(records),
+		Counts:        map[Kind]int{},
+		Mismatches:    []Mismatch{},
+	}
+
+	byNumber := make(map[string]*alfapay.GetOrderStatusExtendedResponse, len(orders))
+	byID := make(map[string]*alfapay.GetOrderStatusExtendedResponse, len(orders))
+	for _, order := range orders {
+		byNumber[order.OrderNumber] = order
+		if id := order.OrderID(); id != "" {
+			byID[id] = order
+		}
+	}
+
+	seenRecords := make(map[string]bool, len(records))
+	seenOrders := make(map[*alfapay.GetOrderStatusExtendedResponse]bool, len(orders))
+	for i := range records {
+		rec := &records[i]
+		if seenRecords[rec.OrderNumber] {
+			report.add(Mismatch{Kind: DuplicateInLedger, OrderNumber: rec.OrderNumber, OrderID: rec.OrderID, Record: rec,
+				Detail: "order number appears more than once in the ledger"})
+			continue
+		}
+		seenRecords[rec.OrderNumber] = true
+
+		order := byID[rec.OrderID]
+		if order == nil {
+			order = byNumber[rec.OrderNumber]
+		}
+		if order == nil {
+			if order, err = r.lookup(ctx, rec); err != nil {
+				return nil, err
+			}
+			if order == nil {
+				report.add(Mismatch{Kind: MissingOnGateway, OrderNumber: rec.OrderNumber, OrderID: rec.OrderID, Record: rec,
+					Ledger: strconv.FormatInt(rec.Amount, 10), Detail: "order not found on the gateway"})
+				continue
+			}
+		}
+		seenOrders[order] = true
+
+		if mismatches := r.compare(rec, order); len(mismatches) > 0 {
+			for _, m := range mismatches {
+				report.add(m)
+			}
+		} else {
+			report.Matched++
+		}
+	}
+
+	for _, order := range orders {
+		if seenOrders[order] || !r.includeUnpaid && unpaid(order.OrderStatus) {
+			continue
+		}
+		report.add(Mismatch{Kind: MissingInLedger, OrderNumber: order.OrderNumber, OrderID: order.OrderID(), Order: order,
+			Gateway: strconv.FormatInt(order.Amount, 10), Detail: "order not found in the ledger"})
+	}
+
+	sort.SliceStable(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].OrderNumber < report.Mismatches[j].OrderNumber
+	})
+	return report, nil
+}
+
+func (r *Report) add(m Mismatch) {
+	r.Mismatches = append(r.Mismatches, m)
+	r.Counts[m.Kind]++
+}
+
+// lookup fetches a ledger order outside the listed period. It returns nil if the gateway does not know it.
+func (r *Reconciler) lookup(ctx context.Context, rec *Record) (*alfapay.GetOrderStatusExtendedResponse, error) {
+	req := &alfapay.GetOrderStatusRequest{OrderNumber: rec.OrderNumber}
+	if rec.OrderID != "" {
+		req = &alfapay.GetOrderStatusRequest{OrderID: rec.OrderID}
+	}
+	order, err := r.client.Status.GetExtended(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up order %s: %w", rec.OrderNumber, err)
+	}
+	if order.IsOrderNotFound() {
+		return nil, nil
+	}
+	if !order.IsSuccess() {
+		return nil, fmt.Errorf("failed to look up order %s: %s", rec.OrderNumber, order.ErrorMessage)
+	}
+	return order, nil
+}
+
+// compare returns the differences between a ledger record and its gateway order.
+func (r *Reconciler) compare(rec *Record, order *alfapay.GetOrderStatusExtendedResponse) []Mismatch {
+	var mismatches []Mismatch
+	add := func(kind Kind, ledger, gateway, detail string) {
+		mismatches = append(mismatches, Mismatch{Kind: kind, OrderNumber: rec.OrderNumber, OrderID: order.OrderID(),
+			Ledger: ledger, Gateway: gateway, Detail: detail, Record: rec, Order: order})
+	}
+
+	if rec.Amount != order.Amount {
+		add(AmountMismatch, strconv.FormatInt(rec.Amount, 10), strconv.FormatInt(order.Amount, 10),
+			fmt.Sprintf("amounts differ by %d", order.Amount-rec.Amount))
+	}
+	if refunded := refundedAmount(order); rec.RefundedAmount != refunded {
+		add(RefundMismatch, strconv.FormatInt(rec.RefundedAmount, 10), strconv.FormatInt(refunded, 10),
+			fmt.Sprintf("refunded totals differ by %d", refunded-rec.RefundedAmount))
+	}
+	if !r.statusMatch(rec.Status, order.OrderStatus) {
+		add(StatusMismatch, strconv.Itoa(int(rec.Status)), strconv.Itoa(int(order.OrderStatus)), "statuses differ")
+	}
+	if chargeback := order.Chargeback; rec.Chargeback != chargeback {
+		detail := "chargeback on the gateway not recorded in the ledger"
+		if !chargeback {
+			detail = "chargeback in the ledger not flagged on the gateway"
+		}
+		add(ChargebackMismatch, strconv.FormatBool(rec.Chargeback), strconv.FormatBool(chargeback), detail)
+	}
+	return mismatches
+}
+
+// refundedAmount returns the refunded total of an order, summing its refunds
+// if the gateway did not report payment amount info.
+func refundedAmount(order *alfapay.GetOrderStatusExtendedResponse) int64 {
+	if order.PaymentAmountInfo != nil {
+		return order.PaymentAmountInfo.RefundedAmount
+	}
+	var total int64
+	for _, refund := range order.Refunds {
+		total += refund.RefundAmount
+	}
+	return total
+}
+
+// unpaid reports whether an order status means no money was ever taken.
+func unpaid(status alfapay.OrderStatus) bool {
+	switch status {
+	case alfapay.OrderStatusRegistered, alfapay.OrderStatusACSAuthorization, alfapay.OrderStatusDeclined:
+		return true
+	default:
+		return false
+	}
+}
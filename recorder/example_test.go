@@ -0,0 +1,96 @@
+package recorder_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/recorder"
+)
+
+func Example_replay() {
+	// Replay a cassette recorded against the sandbox; no network access is needed
+	rec, err := recorder.New("testdata/register_and_status.json", recorder.ModeReplay)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := alfapay.NewClient("your-username", "your-password", alfapay.WithHTTPClient(rec.Client()))
+	ctx := context.Background()
+
+	order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-123",
+		Amount:      100000,
+		ReturnURL:   "https://example.com/success",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	status, err := client.Status.GetByOrderID(ctx, order.OrderID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Order ID:", order.OrderID)
+	fmt.Println("Status:", status.OrderStatus, status.CardAuthInfo.MaskedPan)
+	fmt.Println("Unused interactions:", rec.Unused())
+
+	// Output:
+	// Order ID: 70906e55-7114-41d6-8332-4609dc6590f4
+	// Status: 2 411111**1111
+	// Unused interactions: 0
+}
+
+func Example_record() {
+	// Stand-in for the sandbox gateway
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errorCode":"0","bindings":[{"bindingId":"b-1","maskedPan":"555555**5599"}]}`)
+	}))
+	defer gateway.Close()
+
+	dir, err := os.MkdirTemp("", "cassettes")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassette := filepath.Join(dir, "bindings.json")
+
+	// ModeAuto records on the first run and replays once the cassette exists
+	rec, err := recorder.New(cassette, recorder.ModeAuto)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := alfapay.NewClient("your-username", "your-password",
+		alfapay.WithBaseURL(gateway.URL), alfapay.WithHTTPClient(rec.Client()))
+
+	_, err = client.Bindings.GetByCardOrID(context.Background(), "", "5555555555555599")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		log.Fatal(err)
+	}
+
+	var saved recorder.Cassette
+	data, _ := os.ReadFile(cassette)
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Fatal(err)
+	}
+	query := saved.Interactions[0].Request.Query
+	fmt.Println("userName:", query.Get("userName"))
+	fmt.Println("password:", query.Get("password"))
+	fmt.Println("pan:", query.Get("pan"))
+
+	// Output:
+	// userName: [REDACTED]
+	// password: [REDACTED]
+	// pan: [REDACTED]
+}
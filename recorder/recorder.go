@@ -0,0 +1,403 @@
+// Package recorder provides an HTTP transport that records gateway interactions
+// into cassette files and replays them without network access, for use with
+// alfapay.WithHTTPClient in integration tests.
+//
+// Credentials and card data are scrubbed before anything is written to disk.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether the recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette only; unmatched requests fail.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the network and records them, replacing the cassette.
+	ModeRecord
+	// ModeAuto replays if the cassette file exists and records otherwise.
+	ModeAuto
+)
+
+// Redacted replaces scrubbed values.
+const Redacted = "[REDACTED]"
+
+// ErrNoInteraction is returned in replay mode when no recorded interaction matches a request.
+var ErrNoInteraction = errors.New("recorder: no matching interaction")
+
+// DefaultMatchParams are the parameters compared by the default matcher besides method and path.
+var DefaultMatchParams = []string{"orderId", "orderNumber", "mdOrder", "bindingId", "amount"}
+
+// defaultScrubKeys are query parameters and JSON keys whose values are always redacted.
+var defaultScrubKeys = []string{
+	"userName", "password", "token", "pan", "cvc", "cvv", "expiry", "cardholderName",
+	"$PAN", "$CVC", "$EXPIRY", // Card fields of paymentorder.do
+	"paymentToken", "seToken", "paymentData",
+}
+
+var panPattern = regexp.MustCompile(`\b(\d{6})\d{3,9}(\d{4})\b`)
+
+// Cassette is the file format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction represents a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request represents a recorded, scrubbed request.
+type Request struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Query  url.Values `json:"query,omitempty"`
+	Body   string     `json:"body,omitempty"`
+}
+
+// Response represents a recorded, scrubbed response.
+type Response struct {
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Matcher reports whether a recorded request matches an incoming one.
+// The incoming request is passed scrubbed, in the same form as recorded requests.
+type Matcher func(incoming, recorded Request) bool
+
+// MatchParams returns a matcher comparing method, path and the given query or JSON body parameters.
+func MatchParams(params ...string) Matcher {
+	return func(incoming, recorded Request) bool {
+		if incoming.Method != recorded.Method || incoming.Path != recorded.Path {
+			return false
+		}
+		in, rec := requestParams(incoming), requestParams(recorded)
+		for _, name := range params {
+			if in.Get(name) != rec.Get(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matcher   Matcher
+	scrubKeys map[string]bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// Option is a function that configures a Recorder.
+type Option func(*Recorder)
+
+// WithTransport sets the transport used in record mode. Default: http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithMatcher sets how replayed requests are matched. Default: MatchParams(DefaultMatchParams...).
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// WithScrubKeys adds query parameters and JSON keys to redact.
+func WithScrubKeys(keys ...string) Option {
+	return func(r *Recorder) {
+		for _, key := range keys {
+			r.scrubKeys[strings.ToLower(key)] = true
+		}
+	}
+}
+
+// New creates a recorder for a cassette file.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		matcher:   MatchParams(DefaultMatchParams...),
+		scrubKeys: make(map[string]bool),
+	}
+	for _, key := range defaultScrubKeys {
+		r.scrubKeys[strings.ToLower(key)] = true
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("recorder: failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("recorder: failed to decode cassette: %w", err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Mode returns the effective mode (ModeAuto resolved to record or replay).
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an HTTP client using the recorder, for alfapay.WithHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := r.scrubRequest(req, body)
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode:  resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        string(r.scrubBody(respBody)),
+		},
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay serves the first unused recorded interaction matching the request.
+func (r *Recorder) replay(req *http.Request, incoming Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matcher(incoming, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header)
+		if interaction.Response.ContentType != "" {
+			header.Set("Content-Type", interaction.Response.ContentType)
+		}
+		return &http.Response{
+			StatusCode:    interaction.Response.StatusCode,
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, incoming.Method, incoming.Path)
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(&r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("recorder: failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("recorder: failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("recorder: failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Unused returns the number of recorded interactions not replayed yet,
+// useful to assert that a test made every expected call.
+func (r *Recorder) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Recorder) scrubRequest(req *http.Request, body []byte) Request {
+	query := req.URL.Query()
+	for key, values := range query {
+		for i, value := range values {
+			values[i] = r.scrubValue(key, value)
+		}
+	}
+	if len(query) == 0 {
+		query = nil
+	}
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  query,
+		Body:   string(r.scrubBody(body)),
+	}
+}
+
+func (r *Recorder) scrubValue(key, value string) string {
+	if value != "" && r.scrubKeys[strings.ToLower(key)] {
+		return Redacted
+	}
+	return maskPANs(value)
+}
+
+// scrubBody redacts sensitive JSON keys and masks card numbers anywhere in the body.
+func (r *Recorder) scrubBody(body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // Keep numbers (e.g. epoch millis) exactly as sent
+	if err := dec.Decode(&v); err != nil {
+		return []byte(maskPANs(string(body)))
+	}
+	scrubbed, err := json.Marshal(r.scrubJSON("", v))
+	if err != nil {
+		return []byte(maskPANs(string(body)))
+	}
+	return scrubbed
+}
+
+func (r *Recorder) scrubJSON(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = r.scrubJSON(k, child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.scrubJSON(key, child)
+		}
+		return v
+	case string:
+		return r.scrubValue(key, v)
+	default:
+		if v != nil && r.scrubKeys[strings.ToLower(key)] {
+			return Redacted
+		}
+		return v
+	}
+}
+
+// maskPANs keeps the first six and last four digits of card numbers:
+// 13 to 19 digits starting with 2-6 that pass the Luhn check.
+func maskPANs(s string) string {
+	return panPattern.ReplaceAllStringFunc(s, func(pan string) string {
+		if len(pan) < 13 || len(pan) > 19 || pan[0] < '2' || pan[0] > '6' || !luhn(pan) {
+			return pan
+		}
+		return pan[:6] + strings.Repeat("*", len(pan)-10) + pan[len(pan)-4:]
+	})
+}
+
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// requestParams merges query parameters and top-level JSON body fields.
+func requestParams(req Request) url.Values {
+	params := url.Values{}
+	for key, values := range req.Query {
+		params[key] = values
+	}
+	var body map[string]interface{}
+	if json.Unmarshal([]byte(req.Body), &body) == nil {
+		for key, value := range body {
+			if _, ok := params[key]; !ok {
+				params.Set(key, fmt.Sprint(value))
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,162 @@
+package recorder_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/recorder"
+)
+
+func TestReplayCassette(t *testing.T) {
+	rec, err := recorder.New(filepath.Join("testdata", "register_and_status.json"), recorder.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != recorder.ModeReplay {
+		t.Fatalf("mode = %v, want replay for an existing cassette", rec.Mode())
+	}
+	client := alfapay.NewClient("user-api", "secret", alfapay.WithHTTPClient(rec.Client()))
+	ctx := context.Background()
+
+	// A request that was not recorded is not served
+	if _, err := client.Status.GetByOrderID(ctx, "other-order"); !errors.Is(err, recorder.ErrNoInteraction) {
+		t.Fatalf("unrecorded request error = %v, want ErrNoInteraction", err)
+	}
+
+	order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "ORDER-123",
+		Amount:      100000,
+		ReturnURL:   "https://example.com/success",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderID != "70906e55-7114-41d6-8332-4609dc6590f4" {
+		t.Errorf("order ID = %q", order.OrderID)
+	}
+	if rec.Unused() != 1 {
+		t.Errorf("unused = %d, want 1", rec.Unused())
+	}
+
+	status, err := client.Status.GetByOrderID(ctx, order.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.OrderStatus != alfapay.OrderStatusFullyAuthorized || status.CardAuthInfo.MaskedPan != "411111**1111" {
+		t.Errorf("status = %d, masked PAN %q", status.OrderStatus, status.CardAuthInfo.MaskedPan)
+	}
+	if rec.Unused() != 0 {
+		t.Errorf("unused = %d, want 0", rec.Unused())
+	}
+
+	// Each interaction is replayed once
+	if _, err := client.Status.GetByOrderID(ctx, order.OrderID); !errors.Is(err, recorder.ErrNoInteraction) {
+		t.Errorf("second replay error = %v, want ErrNoInteraction", err)
+	}
+}
+
+// cardGateway stands in for the sandbox, echoing the card number in its responses.
+func cardGateway(calls *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/instantPayment.do":
+			fmt.Fprint(w, `{"errorCode":"0","orderId":"order-1","info":"Payment by card 4111111111111111"}`)
+		default:
+			fmt.Fprint(w, `{"errorCode":"0","redirect":"https://example.com/success"}`)
+		}
+	})
+}
+
+// recordCardPayments makes a card payment through the client and a paymentorder.do call
+// with card fields in the query and the JSON body.
+func recordCardPayments(t *testing.T, rec *recorder.Recorder, baseURL string) (*alfapay.InstantPaymentResponse, int) {
+	t.Helper()
+	client := alfapay.NewClient("user-api", "secret",
+		alfapay.WithBaseURL(baseURL), alfapay.WithHTTPClient(rec.Client()))
+	payment, err := client.Payments.Instant(context.Background(), &alfapay.InstantPaymentRequest{
+		OrderNumber: "ORDER-1",
+		Amount:      1000,
+		ReturnURL:   "https://example.com/success",
+		BindingID:   "binding-1",
+		CVC:         "123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{
+		"$PAN":    {"4111111111111111"},
+		"$CVC":    {"123"},
+		"$EXPIRY": {"203012"},
+	}
+	body := `{"mdOrder":"order-1","card":{"pan":"4111111111111111","cvv":"123","expiry":"12/30"}}`
+	resp, err := rec.Client().Post(baseURL+"/rest/paymentorder.do?"+query.Encode(), "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return payment, resp.StatusCode
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	var calls int32
+	gateway := httptest.NewServer(cardGateway(&calls))
+	cassette := filepath.Join(t.TempDir(), "cassettes", "card_payments.json")
+
+	rec, err := recorder.New(cassette, recorder.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != recorder.ModeRecord {
+		t.Fatalf("mode = %v, want record without a cassette", rec.Mode())
+	}
+	recorded, recordedStatus := recordCardPayments(t, rec, gateway.URL)
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	gateway.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"user-api", "secret", "4111111111111111", `"123"`, "203012", "12/30"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %s:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "411111******1111") {
+		t.Errorf("card number in the response is not masked:\n%s", data)
+	}
+
+	// The second run replays the cassette without the gateway
+	replay, err := recorder.New(cassette, recorder.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Mode() != recorder.ModeReplay {
+		t.Fatalf("mode = %v, want replay once the cassette exists", replay.Mode())
+	}
+	replayed, replayedStatus := recordCardPayments(t, replay, gateway.URL)
+	if replayed.OrderID != recorded.OrderID || replayedStatus != recordedStatus {
+		t.Errorf("replayed %+v (%d), recorded %+v (%d)", replayed, replayedStatus, recorded, recordedStatus)
+	}
+	if replay.Unused() != 0 {
+		t.Errorf("unused = %d, want 0", replay.Unused())
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Errorf("gateway calls = %d, want 2 (record only)", calls)
+	}
+}
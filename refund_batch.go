@@ -0,0 +1,228 @@
+package alfapay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchRefundConcurrency is the default number of refunds executed in parallel.
+const DefaultBatchRefundConcurrency = 4
+
+// BatchRefundOutcome represents the outcome of a single refund in a batch.
+type BatchRefundOutcome string
+
+const (
+	BatchRefundRefunded   BatchRefundOutcome = "refunded"    // Refund accepted by the gateway
+	BatchRefundSkipped    BatchRefundOutcome = "skipped"     // Already refunded, nothing was sent
+	BatchRefundFailed     BatchRefundOutcome = "failed"      // Verification or refund failed
+	BatchRefundUnknown    BatchRefundOutcome = "unknown"     // Refund request failed without a gateway response and was not confirmed
+	BatchRefundNotStarted BatchRefundOutcome = "not_started" // Batch was cancelled before this order
+)
+
+// BatchRefundItem represents an order to refund in a batch.
+type BatchRefundItem struct {
+	OrderID    string
+	Amount     int64 // Amount in kopecks; zero refunds the whole remaining amount
+	JSONParams string
+}
+
+// BatchRefundOptions configures RefundService.BatchRefund.
+type BatchRefundOptions struct {
+	Concurrency int // Default: DefaultBatchRefundConcurrency
+
+	// SkipPartiallyRefunded skips orders that already have any refund,
+	// so a batch can be rerun after a crash without refunding twice.
+	SkipPartiallyRefunded bool
+}
+
+// BatchRefundResult represents the result of a single refund in a batch.
+type BatchRefundResult struct {
+	OrderID          string             `json:"orderId"`
+	OrderNumber      string             `json:"orderNumber,omitempty"`
+	Amount           int64              `json:"amount"` // Refunded or requested amount
+	RefundableAmount int64              `json:"refundableAmount"`
+	Outcome          BatchRefundOutcome `json:"outcome"`
+	Reason           string             `json:"reason,omitempty"`
+}
+
+// BatchRefundReport represents the results of a refund batch in input order.
+type BatchRefundReport struct {
+	Results        []BatchRefundResult `json:"results"`
+	Refunded       int                 `json:"refunded"`
+	Skipped        int                 `json:"skipped"`
+	Failed         int                 `json:"failed"`
+	Unknown        int                 `json:"unknown"`
+	NotStarted     int                 `json:"notStarted"`
+	RefundedAmount int64               `json:"refundedAmount"`
+}
+
+// BatchRefund refunds many orders with a bounded worker pool.
+// Each order is verified with Status.GetExtended first: fully refunded orders are skipped
+// and amounts above the refundable remainder are rejected without calling the gateway.
+// A refund request that fails without a gateway response is re-checked by status; if the refund
+// is not confirmed it is reported as BatchRefundUnknown, and must be reconciled before rerunning.
+// Cancelling the context stops starting new refunds; the report is returned with the context error.
+func (s *RefundService) BatchRefund(ctx context.Context, items []BatchRefundItem, opts BatchRefundOptions) (*BatchRefundReport, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchRefundConcurrency
+	}
+
+	results := make([]BatchRefundResult, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		results[i] = BatchRefundResult{
+			OrderID: item.OrderID,
+			Amount:  item.Amount,
+			Outcome: BatchRefundNotStarted,
+		}
+		if seen[item.OrderID] {
+			results[i].Outcome = BatchRefundFailed
+			results[i].Reason = "duplicate order in batch"
+		}
+		seen[item.OrderID] = true
+	}
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.refundOne(ctx, items[i], opts)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range items {
+		if results[i].Outcome == BatchRefundFailed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &BatchRefundReport{Results: results}
+	for _, r := range results {
+		switch r.Outcome {
+		case BatchRefundRefunded:
+			report.Refunded++
+			report.RefundedAmount += r.Amount
+		case BatchRefundSkipped:
+			report.Skipped++
+		case BatchRefundFailed:
+			report.Failed++
+		case BatchRefundUnknown:
+			report.Unknown++
+		case BatchRefundNotStarted:
+			report.NotStarted++
+		}
+	}
+	return report, ctx.Err()
+}
+
+// refundOne verifies the refundable amount of an order and refunds it.
+func (s *RefundService) refundOne(ctx context.Context, item BatchRefundItem, opts BatchRefundOptions) BatchRefundResult {
+	result := BatchRefundResult{OrderID: item.OrderID, Amount: item.Amount}
+	fail := func(format string, args ...interface{}) BatchRefundResult {
+		result.Outcome = BatchRefundFailed
+		result.Reason = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	if item.Amount < 0 {
+		return fail("invalid amount %d", item.Amount)
+	}
+
+	status, err := s.client.Status.GetExtended(ctx, &GetOrderStatusRequest{OrderID: item.OrderID})
+	if err != nil {
+		return fail("failed to get order status: %v", err)
+	}
+	if !status.IsSuccess() {
+		return fail("failed to get order status: %s", status.ErrorMessage)
+	}
+	result.OrderNumber = status.OrderNumber
+
+	var deposited, refunded int64
+	if status.PaymentAmountInfo != nil {
+		deposited = status.PaymentAmountInfo.DepositedAmount
+		refunded = status.PaymentAmountInfo.RefundedAmount
+	}
+	result.RefundableAmount = deposited - refunded
+
+	switch {
+	case status.OrderStatus == OrderStatusRefunded && result.RefundableAmount <= 0,
+		refunded > 0 && result.RefundableAmount <= 0:
+		result.Outcome = BatchRefundSkipped
+		result.Reason = "already refunded"
+		return result
+	case opts.SkipPartiallyRefunded && refunded > 0:
+		result.Outcome = BatchRefundSkipped
+		result.Reason = fmt.Sprintf("already refunded %d", refunded)
+		return result
+	case status.OrderStatus != OrderStatusFullyAuthorized && status.OrderStatus != OrderStatusRefunded:
+		return fail("order is not deposited (status %d)", status.OrderStatus)
+	case result.RefundableAmount <= 0:
+		return fail("nothing to refund")
+	}
+
+	amount := item.Amount
+	if amount == 0 {
+		amount = result.RefundableAmount
+	}
+	result.Amount = amount
+	if amount > result.RefundableAmount {
+		return fail("amount %d exceeds refundable %d", amount, result.RefundableAmount)
+	}
+
+	resp, err := s.Refund(ctx, &RefundRequest{
+		OrderID:    item.OrderID,
+		Amount:     amount,
+		JSONParams: item.JSONParams,
+	})
+	if err != nil && isAmbiguousFailure(err) {
+		return s.recheckRefund(ctx, result, refunded, err)
+	}
+	if err != nil {
+		return fail("refund failed: %v", err)
+	}
+	if !resp.IsSuccess() {
+		return fail("refund failed: %s", resp.ErrorMessage)
+	}
+
+	result.Outcome = BatchRefundRefunded
+	result.RefundableAmount -= amount
+	return result
+}
+
+// recheckRefund looks up the order after a refund request failed without a gateway response.
+// The refund is confirmed if the refunded amount grew by at least the requested amount since
+// the verification; otherwise its outcome is unknown.
+func (s *RefundService) recheckRefund(ctx context.Context, result BatchRefundResult, refundedBefore int64, refundErr error) BatchRefundResult {
+	lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotentLookupTimeout)
+	defer cancel()
+
+	result.Outcome = BatchRefundUnknown
+	status, err := s.client.Status.GetExtended(lookupCtx, &GetOrderStatusRequest{OrderID: result.OrderID})
+	switch {
+	case err != nil:
+		result.Reason = fmt.Sprintf("refund outcome unknown: %v; status check failed: %v", refundErr, err)
+	case !status.IsSuccess():
+		result.Reason = fmt.Sprintf("refund outcome unknown: %v; status check failed: %s", refundErr, status.ErrorMessage)
+	case status.PaymentAmountInfo != nil && status.PaymentAmountInfo.RefundedAmount-refundedBefore >= result.Amount:
+		result.Outcome = BatchRefundRefunded
+		result.RefundableAmount = status.PaymentAmountInfo.DepositedAmount - status.PaymentAmountInfo.RefundedAmount
+	default:
+		result.Reason = fmt.Sprintf("refund outcome unknown: %v; not refunded yet", refundErr)
+	}
+	return result
+}
@@ -30,6 +30,7 @@ func (s *RefundService) Refund(ctx context.Context, req *RefundRequest) (*BaseRe
 
 	var resp BaseResponse
 	err := s.client.doFormRequest(ctx, "/rest/refund.do", params, &resp)
+	s.client.statusCache.invalidate(req.OrderID, "")
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +46,7 @@ func (s *RefundService) InstantRefund(ctx context.Context, orderID string, amoun
 
 	var resp BaseResponse
 	err := s.client.doFormRequest(ctx, "/rest/instantRefund.do", params, &resp)
+	s.client.statusCache.invalidate(orderID, "")
 	if err != nil {
 		return nil, err
 	}
@@ -2,8 +2,10 @@ package alfapay
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 // SBPService handles SBP (Система быстрых платежей / Fast Payment System) operations.
@@ -100,6 +102,122 @@ func (s *SBPService) GetBindings(ctx context.Context, clientID string) (*SBPGetB
 	return &resp, nil
 }
 
+// GetSubscriptionQR retrieves a QR code asking the payer to consent to an SBP subscription.
+// Once the payer consents, the subscription becomes active and a binding is created for Recurrent.
+func (s *SBPService) GetSubscriptionQR(ctx context.Context, req *SBPSubscriptionQRRequest) (*SBPSubscriptionQRResponse, error) {
+	params := url.Values{}
+	params.Set("clientId", req.ClientID)
+
+	if req.MDOrder != "" {
+		params.Set("mdOrder", req.MDOrder)
+	}
+	if req.SubscriptionPurpose != "" {
+		params.Set("subscriptionPurpose", req.SubscriptionPurpose)
+	}
+	if req.RedirectURL != "" {
+		params.Set("redirectUrl", req.RedirectURL)
+	}
+	if req.QRHeight > 0 {
+		params.Set("qrHeight", strconv.Itoa(req.QRHeight))
+	}
+	if req.QRWidth > 0 {
+		params.Set("qrWidth", strconv.Itoa(req.QRWidth))
+	}
+	if req.QRFormat != "" {
+		params.Set("qrFormat", req.QRFormat)
+	}
+
+	var resp SBPSubscriptionQRResponse
+	err := s.client.doFormRequest(ctx, "/rest/sbp/c2b/qr/subscription/get.do", params, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSubscriptionStatus retrieves the status of an SBP subscription by its QR ID.
+func (s *SBPService) GetSubscriptionStatus(ctx context.Context, qrID string) (*SBPSubscriptionStatusResponse, error) {
+	params := url.Values{}
+	params.Set("qrId", qrID)
+
+	var resp SBPSubscriptionStatusResponse
+	err := s.client.doFormRequest(ctx, "/rest/sbp/c2b/qr/subscription/status.do", params, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Recurrent performs a recurrent (subscription) payment using an SBP binding.
+// It is the SBP counterpart of PaymentService.Recurrent.
+func (s *SBPService) Recurrent(ctx context.Context, req *SBPRecurrentPaymentRequest) (*SBPRecurrentPaymentResponse, error) {
+	params := url.Values{}
+	params.Set("orderNumber", req.OrderNumber)
+	params.Set("bindingId", req.BindingID)
+	params.Set("amount", strconv.FormatInt(req.Amount, 10))
+
+	if req.Currency != "" {
+		params.Set("currency", req.Currency)
+	}
+	if req.Description != "" {
+		params.Set("description", req.Description)
+	}
+	if req.ClientID != "" {
+		params.Set("clientId", req.ClientID)
+	}
+	if req.Language != "" {
+		params.Set("language", req.Language)
+	}
+	if req.DynamicCallbackURL != "" {
+		params.Set("dynamicCallbackUrl", req.DynamicCallbackURL)
+	}
+
+	var resp SBPRecurrentPaymentResponse
+	err := s.client.doFormRequest(ctx, "/rest/sbp/c2b/recurrentPayment.do", params, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RefundSummary returns the deposited, refunded and remaining refundable amounts of an SBP QR order.
+// SBP payments are refunded with Refunds.Refund (refund.do), like card payments; the summary
+// tracks partial refunds against the original QR order.
+func (s *SBPService) RefundSummary(ctx context.Context, mdOrder string) (*SBPRefundSummary, error) {
+	status, err := s.client.Status.GetByOrderID(ctx, mdOrder)
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsSuccess() {
+		return nil, fmt.Errorf("failed to get status of order %s: %s", mdOrder, status.ErrorMessage)
+	}
+	if status.PaymentWay != "" && !strings.Contains(strings.ToUpper(status.PaymentWay), "SBP") {
+		return nil, fmt.Errorf("order %s was paid via %s, not SBP; use Refunds.Refund", mdOrder, status.PaymentWay)
+	}
+
+	summary := &SBPRefundSummary{
+		OrderID: mdOrder,
+		Refunds: status.Refunds,
+	}
+	if status.PaymentAmountInfo != nil {
+		summary.AmountsKnown = true
+		summary.DepositedAmount = status.PaymentAmountInfo.DepositedAmount
+		summary.RefundedAmount = status.PaymentAmountInfo.RefundedAmount
+	}
+	summary.RefundableAmount = summary.DepositedAmount - summary.RefundedAmount
+	return summary, nil
+}
+
+// GetBanks retrieves the list of SBP participant banks.
+func (s *SBPService) GetBanks(ctx context.Context) (*SBPBanksResponse, error) {
+	var resp SBPBanksResponse
+	err := s.client.doFormRequest(ctx, "/rest/sbp/getMembers.do", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // SBP B2B (Business to Business) operations
 
 // B2BGetPayload retrieves payload for B2B SBP payment.
@@ -138,6 +256,8 @@ func (s *SBPService) B2CPerformPayout(ctx context.Context, req *SBPB2CPayoutRequ
 }
 
 // B2CCheckPayout checks the status of a B2C SBP payout.
+//
+// Deprecated: Use B2CGetPayoutStatus to track a payout, or B2CPreCheckPayout to check one before it is performed.
 func (s *SBPService) B2CCheckPayout(ctx context.Context, orderID string) (*SBPB2CCheckPayoutResponse, error) {
 	params := url.Values{}
 	params.Set("orderId", orderID)
@@ -150,6 +270,17 @@ func (s *SBPService) B2CCheckPayout(ctx context.Context, orderID string) (*SBPB2
 	return &resp, nil
 }
 
+// B2CPreCheckPayout checks a B2C SBP payout before it is performed.
+// The response carries the recipient name, which should be confirmed before calling B2CPerformPayout.
+func (s *SBPService) B2CPreCheckPayout(ctx context.Context, req *SBPB2CPayoutRequest) (*SBPB2CCheckPayoutResponse, error) {
+	var resp SBPB2CCheckPayoutResponse
+	err := s.client.doJSONRequest(ctx, "/rest/sbp/b2c/checkPayout.do", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // B2CGetPayoutStatus retrieves the status of a B2C SBP payout.
 func (s *SBPService) B2CGetPayoutStatus(ctx context.Context, orderID string) (*SBPB2CPayoutStatusResponse, error) {
 	params := url.Values{}
@@ -0,0 +1,267 @@
+package alfapay
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SBPBankLogoURL is the NSPK logo URL pattern; %s is replaced with the member ID.
+const SBPBankLogoURL = "https://qr.nspk.ru/proxyapp/logo/bank%s.png"
+
+//go:embed sbp_banks.json
+var sbpBanksSnapshot []byte
+
+// sbpBankDirectoryFile is the JSON layout of a bank directory snapshot.
+type sbpBankDirectoryFile struct {
+	UpdatedAt string    `json:"updatedAt,omitempty"`
+	Banks     []SBPBank `json:"banks"`
+}
+
+// SBPBankDirectory is a lookup table of SBP participant banks.
+// It is safe for concurrent use and can be refreshed while in use.
+type SBPBankDirectory struct {
+	mu        sync.RWMutex
+	updatedAt string
+	banks     []SBPBank
+	byID      map[string]int
+	byBIC     map[string]int
+}
+
+// DefaultSBPBankDirectory returns a directory loaded from the embedded snapshot.
+// The snapshot covers the largest participants; use Refresh for the full list.
+func DefaultSBPBankDirectory() *SBPBankDirectory {
+	d := &SBPBankDirectory{}
+	if err := d.Load(bytes.NewReader(sbpBanksSnapshot)); err != nil {
+		panic(fmt.Sprintf("alfapay: invalid embedded sbp bank snapshot: %v", err))
+	}
+	return d
+}
+
+// Load replaces the directory contents with a JSON snapshot
+// in the format {"updatedAt": "...", "banks": [...]}.
+func (d *SBPBankDirectory) Load(r io.Reader) error {
+	var file sbpBankDirectoryFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("failed to decode sbp bank directory: %w", err)
+	}
+	d.set(file.UpdatedAt, file.Banks)
+	return nil
+}
+
+// Save writes the directory contents as a JSON snapshot readable by Load.
+func (d *SBPBankDirectory) Save(w io.Writer) error {
+	d.mu.RLock()
+	file := sbpBankDirectoryFile{UpdatedAt: d.updatedAt, Banks: d.banks}
+	d.mu.RUnlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&file)
+}
+
+// Refresh replaces the directory contents with the bank list from the gateway.
+func (d *SBPBankDirectory) Refresh(ctx context.Context, client *Client, updatedAt string) error {
+	resp, err := client.SBP.GetBanks(ctx)
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("failed to get sbp banks: %s", resp.ErrorMessage)
+	}
+	if len(resp.Banks) == 0 {
+		return fmt.Errorf("failed to get sbp banks: empty list")
+	}
+	d.set(updatedAt, resp.Banks)
+	return nil
+}
+
+func (d *SBPBankDirectory) set(updatedAt string, banks []SBPBank) {
+	sorted := make([]SBPBank, len(banks))
+	copy(sorted, banks)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	byID := make(map[string]int, len(sorted))
+	byBIC := make(map[string]int, len(sorted))
+	for i := range sorted {
+		if sorted[i].LogoURL == "" {
+			sorted[i].LogoURL = fmt.Sprintf(SBPBankLogoURL, sorted[i].MemberID)
+		}
+		byID[sorted[i].MemberID] = i
+		if sorted[i].BIC != "" {
+			byBIC[sorted[i].BIC] = i
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.updatedAt = updatedAt
+	d.banks = sorted
+	d.byID = byID
+	d.byBIC = byBIC
+}
+
+// UpdatedAt returns the snapshot date of the directory contents.
+func (d *SBPBankDirectory) UpdatedAt() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.updatedAt
+}
+
+// All returns all banks sorted by name.
+func (d *SBPBankDirectory) All() []SBPBank {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	banks := make([]SBPBank, len(d.banks))
+	copy(banks, d.banks)
+	return banks
+}
+
+// ByMemberID looks up a bank by its NSPK member ID.
+func (d *SBPBankDirectory) ByMemberID(memberID string) (SBPBank, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	i, ok := d.byID[memberID]
+	if !ok {
+		return SBPBank{}, false
+	}
+	return d.banks[i], true
+}
+
+// ByBIC looks up a bank by its BIC.
+func (d *SBPBankDirectory) ByBIC(bic string) (SBPBank, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	i, ok := d.byBIC[strings.TrimSpace(bic)]
+	if !ok {
+		return SBPBank{}, false
+	}
+	return d.banks[i], true
+}
+
+// Search returns banks matching the query by Russian or English name, best matches first.
+// Matching ignores case, punctuation and the word "bank", and tolerates small typos.
+func (d *SBPBankDirectory) Search(query string, limit int) []SBPBank {
+	q := normalizeBankName(query)
+	if q == "" {
+		return nil
+	}
+
+	type match struct {
+		bank  SBPBank
+		score int
+	}
+
+	d.mu.RLock()
+	var matches []match
+	for _, bank := range d.banks {
+		score := bankNameScore(q, normalizeBankName(bank.Name))
+		if en := bankNameScore(q, normalizeBankName(bank.NameEn)); en > score {
+			score = en
+		}
+		if score > 0 {
+			matches = append(matches, match{bank: bank, score: score})
+		}
+	}
+	d.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	banks := make([]SBPBank, len(matches))
+	for i, m := range matches {
+		banks[i] = m.bank
+	}
+	return banks
+}
+
+// bankNameScore scores how well a normalized query matches a normalized name; zero means no match.
+func bankNameScore(query, name string) int {
+	if name == "" {
+		return 0
+	}
+	switch {
+	case query == name:
+		return 100
+	case strings.HasPrefix(name, query):
+		return 80
+	case strings.Contains(name, query):
+		return 60
+	}
+
+	// Tolerate typos: one edit per four characters of the query.
+	qlen := len([]rune(query))
+	maxDist := qlen / 4
+	if maxDist == 0 {
+		return 0
+	}
+	best := levenshtein(query, name)
+	for _, word := range strings.Fields(name) {
+		// Compare against word prefixes too, so partially typed names still match.
+		w := []rune(word)
+		for n := qlen - 1; n <= qlen+1; n++ {
+			if n < 1 || n > len(w) {
+				continue
+			}
+			if dist := levenshtein(query, string(w[:n])); dist < best {
+				best = dist
+			}
+		}
+	}
+	if best <= maxDist {
+		return 40 - best
+	}
+	return 0
+}
+
+// normalizeBankName lowercases a bank name and strips punctuation and generic words.
+func normalizeBankName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "ё", "е")
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '\'', '«', '»', '(', ')', '.', ',', '-':
+			return ' '
+		}
+		return r
+	}, name)
+
+	var words []string
+	for _, word := range strings.Fields(name) {
+		switch word {
+		case "банк", "bank", "ао", "пао", "ооо", "jsc", "pjsc":
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
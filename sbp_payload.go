@@ -0,0 +1,181 @@
+package alfapay
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SBPQRType represents the type of an SBP QR code.
+type SBPQRType string
+
+const (
+	SBPQRTypeStatic  SBPQRType = "01" // Reusable, registered once for a point of sale
+	SBPQRTypeDynamic SBPQRType = "02" // Single-use, issued per order
+)
+
+// SBPPayloadHost is the NSPK host of SBP payment links.
+const SBPPayloadHost = "qr.nspk.ru"
+
+// ErrSBPPayloadCRC is returned when the payload checksum does not match its contents.
+var ErrSBPPayloadCRC = errors.New("sbp payload checksum mismatch")
+
+var sbpQRCIDPattern = regexp.MustCompile(`^[A-Z0-9]{32}$`)
+
+// SBPPayload represents a parsed SBP payment link (https://qr.nspk.ru/<QRC ID>?...).
+type SBPPayload struct {
+	QRCID    string     // NSPK QR code identifier
+	Type     SBPQRType  // type
+	BankID   string     // bank: NSPK member ID of the merchant's bank
+	Amount   int64      // sum: amount in kopecks, zero when the payer enters it
+	Currency string     // cur
+	CRC      string     // crc: checksum as found in the link
+	Extra    url.Values // Any other query parameters, preserved as-is
+}
+
+// ParseSBPPayload parses an SBP payment link and validates its checksum when present.
+func ParseSBPPayload(raw string) (*SBPPayload, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sbp payload: %w", err)
+	}
+	if u.Scheme != "https" || u.Host != SBPPayloadHost {
+		return nil, fmt.Errorf("invalid sbp payload: unexpected link %s://%s", u.Scheme, u.Host)
+	}
+
+	p := &SBPPayload{QRCID: strings.Trim(u.Path, "/")}
+	if !sbpQRCIDPattern.MatchString(p.QRCID) {
+		return nil, fmt.Errorf("invalid sbp payload: malformed QR code ID %q", p.QRCID)
+	}
+
+	query := u.Query()
+	p.Type = SBPQRType(query.Get("type"))
+	p.BankID = query.Get("bank")
+	p.Currency = query.Get("cur")
+	p.CRC = query.Get("crc")
+	if sum := query.Get("sum"); sum != "" {
+		p.Amount, err = strconv.ParseInt(sum, 10, 64)
+		if err != nil || p.Amount < 0 {
+			return nil, fmt.Errorf("invalid sbp payload: malformed sum %q", sum)
+		}
+	}
+
+	for _, key := range []string{"type", "bank", "sum", "cur", "crc"} {
+		query.Del(key)
+	}
+	if len(query) > 0 {
+		p.Extra = query
+	}
+
+	if p.CRC != "" {
+		i := strings.LastIndex(raw, "&crc=")
+		if i < 0 {
+			i = strings.LastIndex(raw, "?crc=")
+		}
+		if i < 0 || !strings.EqualFold(sbpCRC(raw[:i]), p.CRC) {
+			return nil, ErrSBPPayloadCRC
+		}
+	}
+
+	return p, nil
+}
+
+// String builds the payment link, appending a freshly computed checksum.
+func (p *SBPPayload) String() string {
+	query := make([]string, 0, 4+len(p.Extra))
+	if p.Type != "" {
+		query = append(query, "type="+url.QueryEscape(string(p.Type)))
+	}
+	if p.BankID != "" {
+		query = append(query, "bank="+url.QueryEscape(p.BankID))
+	}
+	if p.Amount > 0 {
+		query = append(query, "sum="+strconv.FormatInt(p.Amount, 10))
+	}
+	if p.Currency != "" {
+		query = append(query, "cur="+url.QueryEscape(p.Currency))
+	}
+	if extra := p.Extra.Encode(); extra != "" {
+		query = append(query, extra)
+	}
+
+	link := "https://" + SBPPayloadHost + "/" + p.QRCID
+	if len(query) == 0 {
+		return link
+	}
+	link += "?" + strings.Join(query, "&")
+	return link + "&crc=" + sbpCRC(link)
+}
+
+// Validate checks that the payload is well-formed for its type.
+func (p *SBPPayload) Validate() error {
+	if !sbpQRCIDPattern.MatchString(p.QRCID) {
+		return fmt.Errorf("malformed QR code ID %q", p.QRCID)
+	}
+	if p.Amount < 0 {
+		return fmt.Errorf("invalid amount %d", p.Amount)
+	}
+	switch p.Type {
+	case SBPQRTypeStatic:
+	case SBPQRTypeDynamic:
+		if p.Amount <= 0 {
+			return errors.New("dynamic sbp payload requires an amount")
+		}
+	default:
+		return fmt.Errorf("unknown sbp QR type %q", p.Type)
+	}
+	return nil
+}
+
+// VerifyAmount checks that the payload requests exactly the expected amount and currency.
+// An empty currency in the payload is treated as RUB.
+func (p *SBPPayload) VerifyAmount(amount int64, currency string) error {
+	if p.Amount != amount {
+		return fmt.Errorf("sbp payload amount %d does not match expected %d", p.Amount, amount)
+	}
+	cur := p.Currency
+	if cur == "" {
+		cur = "RUB"
+	}
+	if currency != "" && !strings.EqualFold(cur, currency) {
+		return fmt.Errorf("sbp payload currency %s does not match expected %s", cur, currency)
+	}
+	return nil
+}
+
+// NewStaticSBPPayload builds a static QR payload for a QR code ID registered with NSPK.
+// An amount of zero lets the payer enter the amount in the banking app.
+func NewStaticSBPPayload(qrcID, bankID string, amount int64) (*SBPPayload, error) {
+	p := &SBPPayload{
+		QRCID:  qrcID,
+		Type:   SBPQRTypeStatic,
+		BankID: bankID,
+		Amount: amount,
+	}
+	if amount > 0 {
+		p.Currency = "RUB"
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// sbpCRC computes the CRC-16/CCITT-FALSE checksum of the link preceding the crc parameter.
+func sbpCRC(s string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%04X", crc)
+}
@@ -0,0 +1,71 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+	"github.com/KlimGrishanov/alfapay/mockgateway"
+)
+
+func TestSBPRefundSummary(t *testing.T) {
+	srv := httptest.NewServer(mockgateway.New(mockgateway.Options{}))
+	defer srv.Close()
+
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL+mockgateway.PathPrefix))
+	ctx := context.Background()
+
+	order, err := client.Orders.Register(ctx, &alfapay.RegisterOrderRequest{
+		OrderNumber: "SBP-REFUND-1",
+		Amount:      150000,
+		ReturnURL:   "https://shop.example.com/return",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SBP.GetQR(ctx, &alfapay.SBPGetQRRequest{MDOrder: order.OrderID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pay the QR as the payer's bank would
+	resp, err := http.PostForm(srv.URL+mockgateway.PathPrefix+"/sbp/pay", url.Values{"mdOrder": {order.OrderID}, "action": {"pay"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	refund, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: order.OrderID, Amount: 50000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refund.IsSuccess() {
+		t.Fatalf("refund rejected: %s", refund.ErrorMessage)
+	}
+
+	summary, err := client.SBP.RefundSummary(ctx, order.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.AmountsKnown {
+		t.Fatal("summary amounts not reported")
+	}
+	if summary.DepositedAmount != 150000 || summary.RefundedAmount != 50000 || summary.RefundableAmount != 100000 {
+		t.Errorf("summary = deposited %d, refunded %d, refundable %d; want 150000, 50000, 100000",
+			summary.DepositedAmount, summary.RefundedAmount, summary.RefundableAmount)
+	}
+	if len(summary.Refunds) != 1 || summary.Refunds[0].RefundAmount != 50000 {
+		t.Errorf("summary refunds = %+v, want one refund of 50000", summary.Refunds)
+	}
+
+	// Refunding more than what is left must be rejected by the gateway
+	over, err := client.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: order.OrderID, Amount: summary.RefundableAmount + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if over.IsSuccess() {
+		t.Error("refund above the refundable amount succeeded")
+	}
+}
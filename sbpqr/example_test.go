@@ -0,0 +1,52 @@
+package sbpqr_test
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+
+	"github.com/KlimGrishanov/alfapay/sbpqr"
+)
+
+func Example_render() {
+	// Payload of SBPService.GetQR or SBPService.B2BGetPayload
+	payload := "https://qr.nspk.ru/AD10006M8KH4MCG38T0BBF6GMONNGBO3?type=02&bank=100000000008&sum=50000&cur=RUB&crc=AB75"
+
+	// Large PNG for the kiosk screen
+	kiosk, err := sbpqr.PNG(payload, sbpqr.WithSize(1024))
+	if err != nil {
+		log.Fatalf("Failed to render QR: %v", err)
+	}
+	if err := os.WriteFile("kiosk.png", kiosk, 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	// Compact SVG for receipts, with a logo overlay
+	receipt, err := sbpqr.SVG(payload,
+		sbpqr.WithSize(160),
+		sbpqr.WithMargin(2),
+		sbpqr.WithColors(color.RGBA{R: 0x1d, G: 0x1d, B: 0x1b, A: 0xff}, color.White),
+		sbpqr.WithLogoHref("https://your-site.com/static/sbp-logo.svg"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to render QR: %v", err)
+	}
+
+	fmt.Printf("Receipt QR: %d bytes\n", len(receipt))
+}
+
+func ExampleEncode() {
+	code, err := sbpqr.Encode("https://qr.nspk.ru/AD10006M8KH4MCG38T0BBF6GMONNGBO3?type=02&bank=100000000008&sum=50000&cur=RUB&crc=AB75")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	img := code.Image()
+	fmt.Printf("Modules per side: %d\n", code.Modules())
+	fmt.Printf("Image size: %dx%d\n", img.Bounds().Dx(), img.Bounds().Dy())
+
+	// Output:
+	// Modules per side: 49
+	// Image size: 256x256
+}
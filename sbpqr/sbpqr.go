@@ -0,0 +1,285 @@
+// Package sbpqr renders SBP (Fast Payment System) QR codes locally from the
+// payload returned by SBPService.GetQR or SBPService.B2BGetPayload.
+//
+// It is a separate module, so the QR encoder it depends on is not required by
+// the alfapay module itself. The SBP logo is not bundled: get the artwork from
+// the NSPK brand guidelines and pass it with WithLogo or WithLogoHref.
+package sbpqr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// DefaultSize is the default image size in pixels.
+	DefaultSize = 256
+	// DefaultMargin is the default quiet zone width in modules.
+	DefaultMargin = 4
+	// DefaultLogoScale is the default logo size relative to the image size.
+	DefaultLogoScale = 0.2
+)
+
+// Code is an encoded SBP QR code ready to be rendered.
+type Code struct {
+	modules [][]bool
+
+	size       int
+	margin     int
+	foreground color.Color
+	background color.Color
+	logo       image.Image
+	logoHref   string
+	logoScale  float64
+}
+
+// Option is a function that configures rendering.
+type Option func(*Code)
+
+// WithSize sets the image size in pixels (PNG) or user units (SVG).
+func WithSize(size int) Option {
+	return func(c *Code) {
+		c.size = size
+	}
+}
+
+// WithMargin sets the quiet zone width in modules.
+func WithMargin(margin int) Option {
+	return func(c *Code) {
+		c.margin = margin
+	}
+}
+
+// WithColors sets the module and background colors.
+func WithColors(foreground, background color.Color) Option {
+	return func(c *Code) {
+		c.foreground = foreground
+		c.background = background
+	}
+}
+
+// WithLogo overlays a logo (e.g. the SBP logo) in the center of PNG output.
+func WithLogo(logo image.Image) Option {
+	return func(c *Code) {
+		c.logo = logo
+	}
+}
+
+// WithLogoHref overlays a logo in the center of SVG output.
+// The href may be a URL or a data URI.
+func WithLogoHref(href string) Option {
+	return func(c *Code) {
+		c.logoHref = href
+	}
+}
+
+// WithLogoScale sets the logo size relative to the image size (0 < scale <= 0.3).
+func WithLogoScale(scale float64) Option {
+	return func(c *Code) {
+		c.logoScale = scale
+	}
+}
+
+// Encode encodes an SBP payload (https://qr.nspk.ru/... link) into a QR code.
+// High error correction is used when a logo is set so the code stays readable.
+func Encode(payload string, opts ...Option) (*Code, error) {
+	if payload == "" {
+		return nil, errors.New("sbpqr: empty payload")
+	}
+
+	c := &Code{
+		size:       DefaultSize,
+		margin:     DefaultMargin,
+		foreground: color.Black,
+		background: color.White,
+		logoScale:  DefaultLogoScale,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.size <= 0 {
+		return nil, fmt.Errorf("sbpqr: invalid size %d", c.size)
+	}
+	if c.margin < 0 {
+		return nil, fmt.Errorf("sbpqr: invalid margin %d", c.margin)
+	}
+	if c.logoScale <= 0 || c.logoScale > 0.3 {
+		return nil, fmt.Errorf("sbpqr: invalid logo scale %v", c.logoScale)
+	}
+
+	level := qrcode.Medium
+	if c.logo != nil || c.logoHref != "" {
+		level = qrcode.Highest
+	}
+
+	q, err := qrcode.New(payload, level)
+	if err != nil {
+		return nil, fmt.Errorf("sbpqr: failed to encode payload: %w", err)
+	}
+	q.DisableBorder = true
+	c.modules = q.Bitmap()
+
+	return c, nil
+}
+
+// PNG renders the payload as a PNG image.
+func PNG(payload string, opts ...Option) ([]byte, error) {
+	c, err := Encode(payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.PNG()
+}
+
+// SVG renders the payload as an SVG image.
+func SVG(payload string, opts ...Option) ([]byte, error) {
+	c, err := Encode(payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.SVG(), nil
+}
+
+// Modules returns the number of modules per side, including the margin.
+func (c *Code) Modules() int {
+	return len(c.modules) + 2*c.margin
+}
+
+// Image renders the code as an image of the configured size.
+func (c *Code) Image() image.Image {
+	total := c.Modules()
+	scale := c.size / total
+	if scale < 1 {
+		scale = 1
+	}
+	size := c.size
+	if scale*total > size {
+		size = scale * total
+	}
+	offset := (size-scale*total)/2 + c.margin*scale
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.background), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(c.foreground)
+	for y, row := range c.modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			r := image.Rect(offset+x*scale, offset+y*scale, offset+(x+1)*scale, offset+(y+1)*scale)
+			draw.Draw(img, r, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if c.logo != nil {
+		c.drawLogo(img)
+	}
+
+	return img
+}
+
+// PNG renders the code as a PNG image.
+func (c *Code) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WritePNG writes the code as a PNG image.
+func (c *Code) WritePNG(w io.Writer) error {
+	if err := png.Encode(w, c.Image()); err != nil {
+		return fmt.Errorf("sbpqr: failed to encode png: %w", err)
+	}
+	return nil
+}
+
+// SVG renders the code as an SVG image.
+func (c *Code) SVG() []byte {
+	var buf bytes.Buffer
+	_ = c.WriteSVG(&buf)
+	return buf.Bytes()
+}
+
+// WriteSVG writes the code as an SVG image.
+// One SVG user unit of the viewBox corresponds to one module.
+func (c *Code) WriteSVG(w io.Writer) error {
+	total := c.Modules()
+
+	var path strings.Builder
+	for y, row := range c.modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+c.margin, y+c.margin)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		c.size, c.size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, total, total, hexColor(c.background))
+	fmt.Fprintf(&buf, `<path d="%s" fill="%s"/>`, path.String(), hexColor(c.foreground))
+
+	if c.logoHref != "" {
+		logoSize := float64(total) * c.logoScale
+		pad := logoSize * 0.1
+		pos := (float64(total) - logoSize) / 2
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`,
+			pos-pad, pos-pad, logoSize+2*pad, logoSize+2*pad, hexColor(c.background))
+		fmt.Fprintf(&buf, `<image href="%s" x="%.2f" y="%.2f" width="%.2f" height="%.2f"/>`,
+			escapeAttr(c.logoHref), pos, pos, logoSize, logoSize)
+	}
+
+	buf.WriteString(`</svg>`)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("sbpqr: failed to write svg: %w", err)
+	}
+	return nil
+}
+
+// drawLogo draws the logo centered on a background pad, scaled with nearest-neighbor sampling.
+func (c *Code) drawLogo(img *image.RGBA) {
+	size := img.Bounds().Dx()
+	logoSize := int(float64(size) * c.logoScale)
+	if logoSize < 1 {
+		return
+	}
+	pad := logoSize / 10
+	pos := (size - logoSize) / 2
+
+	padRect := image.Rect(pos-pad, pos-pad, pos+logoSize+pad, pos+logoSize+pad)
+	draw.Draw(img, padRect, image.NewUniform(c.background), image.Point{}, draw.Src)
+
+	src := c.logo.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, logoSize, logoSize))
+	for y := 0; y < logoSize; y++ {
+		for x := 0; x < logoSize; x++ {
+			scaled.Set(x, y, c.logo.At(src.Min.X+x*src.Dx()/logoSize, src.Min.Y+y*src.Dy()/logoSize))
+		}
+	}
+	draw.Draw(img, image.Rect(pos, pos, pos+logoSize, pos+logoSize), scaled, image.Point{}, draw.Over)
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+func escapeAttr(s string) string {
+	return strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;").Replace(s)
+}
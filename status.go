@@ -2,18 +2,35 @@ package alfapay
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"strconv"
+	"sync"
 )
 
 // StatusService handles order status operations.
 type StatusService struct {
 	client *Client
+
+	mu       sync.Mutex
+	inflight map[string]*statusCall // GetMany requests in flight, keyed by order ID
 }
 
 // GetExtended retrieves extended order status information.
 // Either orderID or orderNumber must be provided.
+// Responses are served from the cache when it is enabled with WithStatusCache.
 func (s *StatusService) GetExtended(ctx context.Context, req *GetOrderStatusRequest) (*GetOrderStatusExtendedResponse, error) {
+	if req.MerchantLogin == "" && s.client.merchantLogin != "" {
+		r := *req
+		r.MerchantLogin = s.client.merchantLogin
+		req = &r
+	}
+
+	if cached := s.client.statusCache.get(req); cached != nil {
+		return cached, nil
+	}
+	gen := s.client.statusCache.generation()
+
 	params := url.Values{}
 	if req.OrderID != "" {
 		params.Set("orderId", req.OrderID)
@@ -33,6 +50,7 @@ func (s *StatusService) GetExtended(ctx context.Context, req *GetOrderStatusRequ
 	if err != nil {
 		return nil, err
 	}
+	s.client.statusCache.put(req, &resp, gen)
 	return &resp, nil
 }
 
@@ -47,11 +65,11 @@ func (s *StatusService) GetByOrderNumber(ctx context.Context, orderNumber string
 }
 
 // GetLastOrders retrieves orders for a date range.
-// Date format: yyyyMMddHHmmss
+// Date format: yyyyMMddHHmmss, or set FromTime and ToTime instead.
 func (s *StatusService) GetLastOrders(ctx context.Context, req *GetLastOrdersRequest) (*GetLastOrdersResponse, error) {
 	params := url.Values{}
-	params.Set("from", req.FromDate)
-	params.Set("to", req.ToDate)
+	params.Set("from", timeParam(req.FromDate, req.FromTime, FormatLastOrdersDate))
+	params.Set("to", timeParam(req.ToDate, req.ToTime, FormatLastOrdersDate))
 
 	if req.Page > 0 {
 		params.Set("page", strconv.Itoa(req.Page))
@@ -77,6 +95,46 @@ func (s *StatusService) GetLastOrders(ctx context.Context, req *GetLastOrdersReq
 	return &resp, nil
 }
 
+// EachLastOrder calls fn for every order in the range of req, fetching pages from req.Page on
+// until the reported total is reached. It stops at the first error, from the gateway or from fn.
+func (s *StatusService) EachLastOrder(ctx context.Context, req *GetLastOrdersRequest, fn func(*GetOrderStatusExtendedResponse) error) error {
+	page := *req
+	fetched := 0
+	for {
+		resp, err := s.GetLastOrders(ctx, &page)
+		if err != nil {
+			return err
+		}
+		if !resp.IsSuccess() {
+			return fmt.Errorf("failed to get orders page %d: %s", page.Page, resp.ErrorMessage)
+		}
+		for i := range resp.Orders {
+			if err := fn(&resp.Orders[i]); err != nil {
+				return err
+			}
+		}
+
+		fetched += len(resp.Orders)
+
+		size := resp.PageSize
+		if size == 0 {
+			size = page.Size
+		}
+		if size == 0 {
+			// Neither side reported a page size: the first page shows the gateway default
+			size = len(resp.Orders)
+			page.Size = size
+		}
+		if len(resp.Orders) == 0 || len(resp.Orders) < size {
+			return nil
+		}
+		if total := resp.TotalCount; total > 0 && req.Page*size+fetched >= total {
+			return nil
+		}
+		page.Page++
+	}
+}
+
 // VerifyEnrollment checks if a card is enrolled in 3D Secure.
 func (s *StatusService) VerifyEnrollment(ctx context.Context, req *VerifyEnrollmentRequest) (*VerifyEnrollmentResponse, error) {
 	params := url.Values{}
@@ -0,0 +1,122 @@
+package alfapay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultStatusConcurrency is the default number of parallel status requests in GetMany.
+const DefaultStatusConcurrency = 8
+
+// sharedStatusTimeout bounds a status request shared by several callers, which runs without their cancellation.
+const sharedStatusTimeout = 30 * time.Second
+
+// StatusResult represents the status of a single order fetched by GetMany.
+type StatusResult struct {
+	Response *GetOrderStatusExtendedResponse
+	Err      error // Request error or gateway error response
+}
+
+// statusCall is a status request shared by concurrent callers for the same order.
+type statusCall struct {
+	done chan struct{}
+	resp *GetOrderStatusExtendedResponse
+	err  error
+}
+
+// GetMany retrieves extended statuses of many orders in parallel with at most concurrency
+// requests in flight (DefaultStatusConcurrency if not positive).
+// Duplicate IDs, and IDs already being fetched by another GetMany call, are requested only once.
+// Every ID gets an entry in the result; a gateway error response is reported as the item's error.
+func (s *StatusService) GetMany(ctx context.Context, ids []string, concurrency int) map[string]StatusResult {
+	if concurrency <= 0 {
+		concurrency = DefaultStatusConcurrency
+	}
+
+	results := make(map[string]StatusResult, len(ids))
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				resp, err := s.getShared(ctx, id)
+				mu.Lock()
+				results[id] = StatusResult{Response: resp, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	queued := make(map[string]bool, len(ids))
+dispatch:
+	for _, id := range ids {
+		if queued[id] {
+			continue
+		}
+		queued[id] = true
+
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- id:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, id := range ids {
+		if _, ok := results[id]; !ok {
+			results[id] = StatusResult{Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// getShared fetches an order status, joining a request already in flight for the same order.
+// The request runs detached from the caller that started it, so cancelling one caller does not fail
+// the others; each caller gets its own copy of the response.
+func (s *StatusService) getShared(ctx context.Context, orderID string) (*GetOrderStatusExtendedResponse, error) {
+	s.mu.Lock()
+	if s.inflight == nil {
+		s.inflight = make(map[string]*statusCall)
+	}
+	call, ok := s.inflight[orderID]
+	if !ok {
+		call = &statusCall{done: make(chan struct{})}
+		s.inflight[orderID] = call
+		go s.fetchShared(context.WithoutCancel(ctx), orderID, call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		if call.resp == nil {
+			return nil, call.err
+		}
+		return cloneOrderStatus(call.resp), call.err
+	}
+}
+
+// fetchShared performs the request of a shared status call.
+func (s *StatusService) fetchShared(ctx context.Context, orderID string, call *statusCall) {
+	ctx, cancel := context.WithTimeout(ctx, sharedStatusTimeout)
+	defer cancel()
+
+	call.resp, call.err = s.GetByOrderID(ctx, orderID)
+	if call.err == nil && !call.resp.IsSuccess() {
+		call.err = fmt.Errorf("failed to get status of order %s: %s", orderID, call.resp.ErrorMessage)
+	}
+
+	s.mu.Lock()
+	delete(s.inflight, orderID)
+	s.mu.Unlock()
+	close(call.done)
+}
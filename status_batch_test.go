@@ -0,0 +1,54 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+func TestGetManySharedRequest(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write([]byte(`{"errorCode":"0","orderNumber":"ORDER-1","orderStatus":2,"cardAuthInfo":{"maskedPan":"411111**1111"}}`))
+	}))
+	defer srv.Close()
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL))
+
+	// The first caller starts the request and gives up before it completes
+	cancelled, cancel := context.WithCancel(context.Background())
+	first := make(chan map[string]alfapay.StatusResult)
+	go func() { first <- client.Status.GetMany(cancelled, []string{"order-1"}, 1) }()
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan map[string]alfapay.StatusResult)
+	go func() { second <- client.Status.GetMany(context.Background(), []string{"order-1", "order-1"}, 2) }()
+	third := make(chan map[string]alfapay.StatusResult)
+	go func() { third <- client.Status.GetMany(context.Background(), []string{"order-1"}, 1) }()
+
+	cancel()
+	if res := (<-first)["order-1"]; res.Err == nil {
+		t.Errorf("cancelled caller got %+v, want its context error", res)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the other callers join the request in flight
+	close(release)
+
+	a, b := (<-second)["order-1"], (<-third)["order-1"]
+	if a.Err != nil || b.Err != nil {
+		t.Fatalf("errors after the first caller was cancelled: %v, %v", a.Err, b.Err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests sent, want 1 shared request", n)
+	}
+	if a.Response == b.Response || a.Response.CardAuthInfo == b.Response.CardAuthInfo {
+		t.Error("callers share the same response; each must get its own copy")
+	}
+}
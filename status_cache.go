@@ -0,0 +1,250 @@
+package alfapay
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStatusCacheTTL is the default cache lifetime of orders that can still change status.
+	DefaultStatusCacheTTL = 5 * time.Second
+	// DefaultStatusCacheTerminalTTL is the default cache lifetime of orders in a terminal status.
+	DefaultStatusCacheTerminalTTL = 5 * time.Minute
+	// DefaultStatusCacheMaxEntries is the default maximum number of cached responses.
+	DefaultStatusCacheMaxEntries = 10000
+)
+
+// StatusCacheOptions configures the order status cache enabled with WithStatusCache.
+type StatusCacheOptions struct {
+	TTL         time.Duration // Non-terminal statuses. Default: DefaultStatusCacheTTL
+	TerminalTTL time.Duration // Cancelled, refunded and declined orders. Default: DefaultStatusCacheTerminalTTL
+	MaxEntries  int           // Default: DefaultStatusCacheMaxEntries
+}
+
+// WithStatusCache enables a short-lived cache of successful StatusService.GetExtended responses.
+// Cached entries of an order are dropped after Deposit, Reverse, Refund, InstantRefund
+// and Decline calls made through the same client.
+func WithStatusCache(opts StatusCacheOptions) ClientOption {
+	return func(c *Client) {
+		c.statusCache = newStatusCache(opts)
+	}
+}
+
+// statusCache caches extended status responses keyed by request parameters.
+type statusCache struct {
+	ttl         time.Duration
+	terminalTTL time.Duration
+	maxEntries  int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*statusCacheEntry
+
+	// Invalidations are numbered by seq. A response fetched before the last invalidation
+	// of its order is not cached, so a request in flight cannot restore a stale status.
+	seq         uint64
+	invalidated map[string]uint64 // Last invalidation by "id:" or "num:" key
+	floor       uint64            // Responses fetched before floor are not cached
+}
+
+type statusCacheEntry struct {
+	orderID     string
+	orderNumber string
+	resp        GetOrderStatusExtendedResponse
+	expires     time.Time
+}
+
+func newStatusCache(opts StatusCacheOptions) *statusCache {
+	c := &statusCache{
+		ttl:         opts.TTL,
+		terminalTTL: opts.TerminalTTL,
+		maxEntries:  opts.MaxEntries,
+		now:         time.Now,
+		entries:     make(map[string]*statusCacheEntry),
+		invalidated: make(map[string]uint64),
+	}
+	if c.ttl <= 0 {
+		c.ttl = DefaultStatusCacheTTL
+	}
+	if c.terminalTTL <= 0 {
+		c.terminalTTL = DefaultStatusCacheTerminalTTL
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = DefaultStatusCacheMaxEntries
+	}
+	return c
+}
+
+func statusCacheKey(req *GetOrderStatusRequest) string {
+	return req.OrderID + "|" + req.OrderNumber + "|" + req.Language + "|" + req.MerchantLogin
+}
+
+// get returns a copy of a cached response, or nil. A nil cache never hits.
+func (c *statusCache) get(req *GetOrderStatusRequest) *GetOrderStatusExtendedResponse {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := statusCacheKey(req)
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return cloneOrderStatus(&entry.resp)
+}
+
+// generation returns the current invalidation sequence, to be passed to put
+// with the response of a request started now.
+func (c *statusCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq
+}
+
+// put caches a successful response with a TTL depending on its status.
+// The response is dropped if its order was invalidated after generation gen.
+func (c *statusCache) put(req *GetOrderStatusRequest, resp *GetOrderStatusExtendedResponse, gen uint64) {
+	if c == nil || resp == nil || !resp.IsSuccess() {
+		return
+	}
+
+	ttl := c.ttl
+	if resp.OrderStatus.IsTerminal() {
+		ttl = c.terminalTTL
+	}
+
+	entry := &statusCacheEntry{
+		orderID:     req.OrderID,
+		orderNumber: req.OrderNumber,
+		resp:        *cloneOrderStatus(resp),
+	}
+	if entry.orderID == "" {
+		entry.orderID = resp.OrderID()
+	}
+	if entry.orderNumber == "" {
+		entry.orderNumber = resp.OrderNumber
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen < c.floor || c.invalidated["id:"+entry.orderID] > gen || c.invalidated["num:"+entry.orderNumber] > gen {
+		return
+	}
+
+	now := c.now()
+	entry.expires = now.Add(ttl)
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[statusCacheKey(req)] = entry
+}
+
+// evict drops expired entries, or all entries if none have expired.
+func (c *statusCache) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]*statusCacheEntry)
+	}
+}
+
+// invalidate drops all cached responses of an order identified by ID or number.
+func (c *statusCache) invalidate(orderID, orderNumber string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	if len(c.invalidated) >= c.maxEntries {
+		// Forget individual orders and refuse every response fetched before now instead
+		c.invalidated = make(map[string]uint64)
+		c.floor = c.seq
+	}
+	if orderID != "" {
+		c.invalidated["id:"+orderID] = c.seq
+	}
+	if orderNumber != "" {
+		c.invalidated["num:"+orderNumber] = c.seq
+	}
+
+	for key, entry := range c.entries {
+		if (orderID != "" && entry.orderID == orderID) || (orderNumber != "" && entry.orderNumber == orderNumber) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops cached statuses of an order, e.g. after a callback reports a change.
+// It does nothing if the status cache is not enabled.
+func (s *StatusService) Invalidate(orderID string) {
+	s.client.statusCache.invalidate(orderID, "")
+}
+
+// cloneOrderStatus returns a deep copy of a response, so callers cannot modify cached entries.
+func cloneOrderStatus(resp *GetOrderStatusExtendedResponse) *GetOrderStatusExtendedResponse {
+	c := *resp
+	if resp.CardAuthInfo != nil {
+		info := *resp.CardAuthInfo
+		if info.SecureAuthInfo != nil {
+			secure := *info.SecureAuthInfo
+			secure.ThreeDSInfo = clonePtr(secure.ThreeDSInfo)
+			info.SecureAuthInfo = &secure
+		}
+		c.CardAuthInfo = &info
+	}
+	c.BindingInfo = clonePtr(resp.BindingInfo)
+	c.PaymentAmountInfo = clonePtr(resp.PaymentAmountInfo)
+	c.BankInfo = clonePtr(resp.BankInfo)
+	c.PayerData = clonePtr(resp.PayerData)
+	c.MerchantOrderParams = slices.Clone(resp.MerchantOrderParams)
+	c.Attributes = slices.Clone(resp.Attributes)
+	c.TransactionAttributes = slices.Clone(resp.TransactionAttributes)
+
+	if resp.Refunds != nil {
+		c.Refunds = make([]Refund, len(resp.Refunds))
+		for i, refund := range resp.Refunds {
+			if refund.RefundItems != nil {
+				items := make([]Item, len(refund.RefundItems))
+				for j, item := range refund.RefundItems {
+					item.Quantity = clonePtr(item.Quantity)
+					item.Tax = clonePtr(item.Tax)
+					item.AgentInterest = clonePtr(item.AgentInterest)
+					if item.ItemDetails != nil {
+						item.ItemDetails = &ItemDetails{ItemDetailsParams: slices.Clone(item.ItemDetails.ItemDetailsParams)}
+					}
+					if item.ItemAttributes != nil {
+						item.ItemAttributes = &ItemAttributes{Attributes: slices.Clone(item.ItemAttributes.Attributes)}
+					}
+					items[j] = item
+				}
+				refund.RefundItems = items
+			}
+			c.Refunds[i] = refund
+		}
+	}
+	return &c
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,139 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+// countingTransport counts status requests sent to the gateway.
+type countingTransport struct {
+	statusRequests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, "/getOrderStatusExtended.do") {
+		t.statusRequests.Add(1)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newCachedClient returns a client with the status cache enabled against a gateway stub.
+// Status requests wait on block, if set, before responding.
+func newCachedClient(t *testing.T, block <-chan struct{}) (*alfapay.Client, *countingTransport) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getOrderStatusExtended.do") {
+			if block != nil {
+				<-block
+			}
+			w.Write([]byte(`{"errorCode":"0","orderNumber":"ORDER-1","orderStatus":2,"amount":1000}`))
+			return
+		}
+		w.Write([]byte(`{"errorCode":"0"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	transport := &countingTransport{}
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL),
+		alfapay.WithHTTPClient(&http.Client{Transport: transport}),
+		alfapay.WithStatusCache(alfapay.StatusCacheOptions{}))
+	return client, transport
+}
+
+func getStatus(t *testing.T, client *alfapay.Client) *alfapay.GetOrderStatusExtendedResponse {
+	t.Helper()
+	resp, err := client.Status.GetExtended(context.Background(), &alfapay.GetOrderStatusRequest{OrderID: "order-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestStatusCacheHit(t *testing.T) {
+	client, transport := newCachedClient(t, nil)
+
+	first := getStatus(t, client)
+	first.OrderNumber = "modified by the caller"
+	second := getStatus(t, client)
+
+	if n := transport.statusRequests.Load(); n != 1 {
+		t.Errorf("%d status requests, want 1", n)
+	}
+	if second.OrderNumber != "ORDER-1" {
+		t.Errorf("cached order number = %q, want ORDER-1", second.OrderNumber)
+	}
+}
+
+func TestStatusCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	calls := map[string]func(*alfapay.Client) error{
+		"Deposit": func(c *alfapay.Client) error {
+			_, err := c.Payments.Deposit(ctx, &alfapay.DepositRequest{OrderID: "order-1", Amount: 1000})
+			return err
+		},
+		"Reverse": func(c *alfapay.Client) error {
+			_, err := c.Payments.Reverse(ctx, &alfapay.ReverseRequest{OrderID: "order-1"})
+			return err
+		},
+		// Also the refund of SBP orders
+		"Refund": func(c *alfapay.Client) error {
+			_, err := c.Refunds.Refund(ctx, &alfapay.RefundRequest{OrderID: "order-1", Amount: 500})
+			return err
+		},
+		"InstantRefund": func(c *alfapay.Client) error {
+			_, err := c.Refunds.InstantRefund(ctx, "order-1", 500)
+			return err
+		},
+		"Decline": func(c *alfapay.Client) error {
+			_, err := c.Orders.Decline(ctx, &alfapay.DeclineRequest{OrderID: "order-1"})
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			client, transport := newCachedClient(t, nil)
+			getStatus(t, client)
+			if err := call(client); err != nil {
+				t.Fatal(err)
+			}
+			getStatus(t, client)
+			if n := transport.statusRequests.Load(); n != 2 {
+				t.Errorf("%d status requests, want 2: the cached status must be dropped", n)
+			}
+		})
+	}
+}
+
+func TestStatusCacheFetchBeforeInvalidation(t *testing.T) {
+	block := make(chan struct{})
+	client, transport := newCachedClient(t, block)
+
+	fetched := make(chan error)
+	go func() {
+		_, err := client.Status.GetExtended(context.Background(), &alfapay.GetOrderStatusRequest{OrderID: "order-1"})
+		fetched <- err
+	}()
+	for transport.statusRequests.Load() == 0 {
+		time.Sleep(time.Millisecond) // Wait until the status request is in flight
+	}
+
+	if _, err := client.Refunds.Refund(context.Background(), &alfapay.RefundRequest{OrderID: "order-1", Amount: 500}); err != nil {
+		t.Fatal(err)
+	}
+	close(block)
+	if err := <-fetched; err != nil {
+		t.Fatal(err)
+	}
+
+	getStatus(t, client)
+	if n := transport.statusRequests.Load(); n != 2 {
+		t.Errorf("%d status requests, want 2: a response fetched before the refund must not be cached", n)
+	}
+}
@@ -0,0 +1,143 @@
+package alfapay
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MoscowTime is the gateway timezone (MSK, UTC+3, no daylight saving time since 2014).
+// Request dates are formatted in this zone and response times are returned in it.
+var MoscowTime = time.FixedZone("MSK", 3*60*60)
+
+const (
+	// DateTimeLayout is the layout of expirationDate, autocompletionDate and autoReverseDate.
+	DateTimeLayout = "2006-01-02T15:04:05"
+	// LastOrdersDateLayout is the layout of GetLastOrdersRequest dates.
+	LastOrdersDateLayout = "20060102150405"
+	// ExpiryLayout is the layout of card and binding expiry months (YYYYMM).
+	ExpiryLayout = "200601"
+)
+
+// FormatDateTime formats a time in Moscow time as yyyy-MM-ddTHH:mm:ss.
+func FormatDateTime(t time.Time) string {
+	return t.In(MoscowTime).Format(DateTimeLayout)
+}
+
+// FormatLastOrdersDate formats a time in Moscow time as yyyyMMddHHmmss.
+func FormatLastOrdersDate(t time.Time) string {
+	return t.In(MoscowTime).Format(LastOrdersDateLayout)
+}
+
+// FormatExpiry formats the month of a time in Moscow time as YYYYMM.
+func FormatExpiry(t time.Time) string {
+	return t.In(MoscowTime).Format(ExpiryLayout)
+}
+
+// ParseExpiry parses a YYYYMM expiry month. The returned time is the first instant
+// after the month ends in Moscow time, i.e. the moment the card or binding expires.
+func ParseExpiry(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(ExpiryLayout, strings.TrimSpace(s), MoscowTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q: %w", s, err)
+	}
+	return t.AddDate(0, 1, 0), nil
+}
+
+// ParseDateTime parses a gateway date string: epoch milliseconds, yyyy-MM-ddTHH:mm:ss
+// or yyyyMMddHHmmss in Moscow time, or RFC 3339.
+func ParseDateTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) != len(LastOrdersDateLayout) {
+		return millisTime(ms), nil
+	}
+	for _, layout := range []string{DateTimeLayout, LastOrdersDateLayout} {
+		if t, err := time.ParseInLocation(layout, s, MoscowTime); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(MoscowTime), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// millisTime converts epoch milliseconds to Moscow time; zero means not set.
+func millisTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).In(MoscowTime)
+}
+
+// timeParam returns the raw value if set, otherwise the formatted time, or "" if neither is set.
+func timeParam(raw string, t time.Time, format func(time.Time) string) string {
+	if raw != "" || t.IsZero() {
+		return raw
+	}
+	return format(t)
+}
+
+// CreatedAt returns the order registration time (Date).
+func (r *GetOrderStatusExtendedResponse) CreatedAt() time.Time {
+	return millisTime(r.Date)
+}
+
+// AuthorizedAt returns the authorization time, or zero if not authorized.
+func (r *GetOrderStatusExtendedResponse) AuthorizedAt() time.Time {
+	return millisTime(r.AuthDateTime)
+}
+
+// DepositedAt returns the deposit time, or zero if not deposited.
+func (r *GetOrderStatusExtendedResponse) DepositedAt() time.Time {
+	return millisTime(r.DepositedDate)
+}
+
+// RefundedAt returns the time of the last refund, or zero if not refunded.
+func (r *GetOrderStatusExtendedResponse) RefundedAt() time.Time {
+	return millisTime(r.RefundedDate)
+}
+
+// ReversedAt returns the reversal time, or zero if not reversed.
+func (r *GetOrderStatusExtendedResponse) ReversedAt() time.Time {
+	return millisTime(r.ReversedDate)
+}
+
+// RefundedAt returns the refund time.
+func (r *Refund) RefundedAt() time.Time {
+	return millisTime(r.RefundDate)
+}
+
+// AuthorizedAt parses the binding authorization time, or returns zero if not set.
+func (i *CardBindingInfo) AuthorizedAt() (time.Time, error) {
+	if i.AuthDateTime == "" {
+		return time.Time{}, nil
+	}
+	return ParseDateTime(i.AuthDateTime)
+}
+
+// ExpiresAt parses the card expiry month (Expiration, YYYYMM).
+func (i *CardAuthInfo) ExpiresAt() (time.Time, error) {
+	return ParseExpiry(i.Expiration)
+}
+
+// CreatedAt returns the binding creation time.
+func (b *Binding) CreatedAt() time.Time {
+	return millisTime(b.CreatedDate)
+}
+
+// LastUsedAt returns the time the binding was last used, or zero if never used.
+func (b *Binding) LastUsedAt() time.Time {
+	return millisTime(b.LastUsedDate)
+}
+
+// ExpiresAt parses the binding expiry month (ExpiryDate, YYYYMM).
+func (b *Binding) ExpiresAt() (time.Time, error) {
+	return ParseExpiry(b.ExpiryDate)
+}
+
+// CreatedAt returns the SBP binding creation time.
+func (b *SBPBinding) CreatedAt() time.Time {
+	return millisTime(b.CreatedDate)
+}
@@ -0,0 +1,326 @@
+package alfapay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WalletProvider identifies a wallet payment provider.
+type WalletProvider string
+
+const (
+	WalletApplePay   WalletProvider = "applepay"
+	WalletGooglePay  WalletProvider = "googlepay"
+	WalletSamsungPay WalletProvider = "samsungpay"
+	WalletMirPay     WalletProvider = "mirpay"
+	WalletYandexPay  WalletProvider = "yandexpay"
+)
+
+// WalletService dispatches wallet payments to the provider-specific services.
+type WalletService struct {
+	client *Client
+}
+
+// Pay performs a wallet payment with the given provider.
+// Use the provider services (client.ApplePay, client.GooglePay, ...) directly
+// together with the WalletPayment conversion methods when provider-specific fields are needed.
+func (s *WalletService) Pay(ctx context.Context, provider WalletProvider, req *WalletPayment) (*WalletResult, error) {
+	if err := req.Validate(provider); err != nil {
+		return nil, err
+	}
+
+	endpoints, ok := walletEndpoints[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown wallet provider: %q", provider)
+	}
+	pay := endpoints.payment
+	if req.Direct {
+		if endpoints.direct == nil {
+			return nil, fmt.Errorf("%s does not support direct payments", provider)
+		}
+		pay = endpoints.direct
+	}
+
+	result, err := pay(ctx, s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	result.Provider = provider
+	return result, nil
+}
+
+// walletEndpoint performs a wallet payment and converts the provider response.
+type walletEndpoint func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error)
+
+// walletEndpoints maps providers to their payment and direct payment endpoints.
+// direct is nil for providers without a paymentDirect.do endpoint.
+var walletEndpoints = map[WalletProvider]struct{ payment, direct walletEndpoint }{
+	WalletApplePay: {
+		payment: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.ApplePay.Payment(ctx, req.ApplePayRequest()))
+		},
+	},
+	WalletGooglePay: {
+		payment: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.GooglePay.Payment(ctx, req.GooglePayRequest()))
+		},
+	},
+	WalletSamsungPay: {
+		payment: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.SamsungPay.Payment(ctx, req.SamsungPayRequest()))
+		},
+		direct: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.SamsungPay.DirectPayment(ctx, req.SamsungPayRequest()))
+		},
+	},
+	WalletMirPay: {
+		payment: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.MirPay.Payment(ctx, req.MirPayRequest()))
+		},
+		direct: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.MirPay.DirectPayment(ctx, req.MirPayRequest()))
+		},
+	},
+	WalletYandexPay: {
+		payment: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.YandexPay.Payment(ctx, req.YandexPayRequest()))
+		},
+		direct: func(ctx context.Context, c *Client, req *WalletPayment) (*WalletResult, error) {
+			return walletResult(c.YandexPay.DirectPayment(ctx, req.YandexPayRequest()))
+		},
+	},
+}
+
+// walletResult converts a provider response returned together with err.
+func walletResult[R interface{ walletResult() *WalletResult }](resp R, err error) (*WalletResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return resp.walletResult(), nil
+}
+
+// walletProviderError lists the provider error types, which all have the fields of WalletError.
+type walletProviderError interface {
+	ApplePayError | GooglePayError | SamsungPayError | MirPayError | YandexPayError
+}
+
+// newWalletResult builds the fields of WalletResult common to all provider responses.
+func newWalletResult[E walletProviderError](raw interface{}, success bool, status *GetOrderStatusExtendedResponse, providerErr *E) *WalletResult {
+	result := &WalletResult{Success: success, OrderStatus: status, Raw: raw}
+	if providerErr != nil {
+		walletErr := WalletError(*providerErr)
+		result.Error = &walletErr
+	}
+	return result
+}
+
+func (r *ApplePayPaymentResponse) walletResult() *WalletResult {
+	result := newWalletResult(r, r.Success, r.OrderStatus, r.Error)
+	if r.Data != nil {
+		result.OrderID = r.Data.OrderID
+	}
+	return result
+}
+
+func (r *GooglePayResponse) walletResult() *WalletResult {
+	result := newWalletResult(r, r.Success, r.OrderStatus, r.Error)
+	if r.Data != nil {
+		result.OrderID = r.Data.OrderID
+	}
+	return result
+}
+
+func (r *SamsungPayPaymentResponse) walletResult() *WalletResult {
+	result := newWalletResult(r, r.Success, r.OrderStatus, r.Error)
+	if r.Data != nil {
+		result.OrderID = r.Data.OrderID
+	}
+	return result
+}
+
+func (r *MirPayResponse) walletResult() *WalletResult {
+	result := newWalletResult(r, r.Success, r.OrderStatus, r.Error)
+	if r.Data != nil {
+		result.OrderID = r.Data.OrderID
+		result.FormURL = r.Data.FormURL
+		result.Deeplink = r.Data.Deeplink
+	}
+	return result
+}
+
+func (r *YandexPayResponse) walletResult() *WalletResult {
+	result := newWalletResult(r, r.Success, r.OrderStatus, r.Error)
+	if r.Data != nil {
+		result.OrderID = r.Data.OrderID
+		result.Redirect = r.Data.Redirect
+		result.AcsURL = r.Data.AcsURL
+		result.PaReq = r.Data.PaReq
+	}
+	return result
+}
+
+// Validate checks that the fields required by the provider are set.
+func (r *WalletPayment) Validate(provider WalletProvider) error {
+	var errs []error
+	if r.Merchant == "" {
+		errs = append(errs, errors.New("merchant is required"))
+	}
+	if r.OrderNumber == "" {
+		errs = append(errs, errors.New("orderNumber is required"))
+	}
+
+	switch provider {
+	case WalletApplePay, WalletSamsungPay:
+		if r.PaymentToken == "" {
+			errs = append(errs, errors.New("paymentToken is required"))
+		}
+	case WalletGooglePay:
+		if r.PaymentToken == "" {
+			errs = append(errs, errors.New("paymentToken is required"))
+		}
+		if r.Amount <= 0 {
+			errs = append(errs, errors.New("amount is required"))
+		}
+		if r.IP == "" {
+			errs = append(errs, errors.New("ip is required"))
+		}
+		if r.ReturnURL == "" {
+			errs = append(errs, errors.New("returnUrl is required"))
+		}
+	case WalletMirPay:
+		if r.Amount <= 0 {
+			errs = append(errs, errors.New("amount is required"))
+		}
+		if r.ReturnURL == "" {
+			errs = append(errs, errors.New("returnUrl is required"))
+		}
+	case WalletYandexPay:
+		if r.PaymentToken == "" {
+			errs = append(errs, errors.New("paymentToken is required"))
+		}
+		if r.Amount <= 0 {
+			errs = append(errs, errors.New("amount is required"))
+		}
+		if r.ReturnURL == "" {
+			errs = append(errs, errors.New("returnUrl is required"))
+		}
+	default:
+		return fmt.Errorf("unknown wallet provider: %q", provider)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid %s payment: %w", provider, errors.Join(errs...))
+	}
+	return nil
+}
+
+// ApplePayRequest converts the payment to an Apple Pay request.
+func (r *WalletPayment) ApplePayRequest() *ApplePayPaymentRequest {
+	return &ApplePayPaymentRequest{
+		Merchant:             r.Merchant,
+		OrderNumber:          r.OrderNumber,
+		PaymentToken:         r.PaymentToken,
+		Description:          r.Description,
+		Language:             r.Language,
+		AdditionalParameters: r.AdditionalParameters,
+		ClientID:             r.ClientID,
+		PreAuth:              r.PreAuth,
+		Email:                r.Email,
+		Phone:                r.Phone,
+		FailURL:              r.FailURL,
+		PostAddress:          r.PostAddress,
+		Amount:               r.Amount,
+		CurrencyCode:         r.CurrencyCode,
+		IP:                   r.IP,
+		ReturnURL:            r.ReturnURL,
+		OrderBundle:          r.OrderBundle,
+	}
+}
+
+// GooglePayRequest converts the payment to a Google Pay request.
+func (r *WalletPayment) GooglePayRequest() *GooglePayRequest {
+	return &GooglePayRequest{
+		Merchant:             r.Merchant,
+		OrderNumber:          r.OrderNumber,
+		PaymentToken:         r.PaymentToken,
+		Amount:               r.Amount,
+		IP:                   r.IP,
+		ReturnURL:            r.ReturnURL,
+		Description:          r.Description,
+		Language:             r.Language,
+		AdditionalParameters: r.AdditionalParameters,
+		ClientID:             r.ClientID,
+		PreAuth:              r.PreAuth,
+		CurrencyCode:         r.CurrencyCode,
+		Email:                r.Email,
+		Phone:                r.Phone,
+		FailURL:              r.FailURL,
+		PostAddress:          r.PostAddress,
+		OrderBundle:          r.OrderBundle,
+	}
+}
+
+// SamsungPayRequest converts the payment to a Samsung Pay request.
+func (r *WalletPayment) SamsungPayRequest() *SamsungPayPaymentRequest {
+	return &SamsungPayPaymentRequest{
+		Merchant:             r.Merchant,
+		OrderNumber:          r.OrderNumber,
+		PaymentToken:         r.PaymentToken,
+		Amount:               r.Amount,
+		IP:                   r.IP,
+		ReturnURL:            r.ReturnURL,
+		Description:          r.Description,
+		Language:             r.Language,
+		AdditionalParameters: r.AdditionalParameters,
+		ClientID:             r.ClientID,
+		PreAuth:              r.PreAuth,
+		CurrencyCode:         r.CurrencyCode,
+		Email:                r.Email,
+		Phone:                r.Phone,
+		FailURL:              r.FailURL,
+		OrderBundle:          r.OrderBundle,
+	}
+}
+
+// MirPayRequest converts the payment to a MIR Pay request.
+func (r *WalletPayment) MirPayRequest() *MirPayPaymentRequest {
+	return &MirPayPaymentRequest{
+		Merchant:             r.Merchant,
+		OrderNumber:          r.OrderNumber,
+		Amount:               r.Amount,
+		ReturnURL:            r.ReturnURL,
+		Description:          r.Description,
+		Language:             r.Language,
+		AdditionalParameters: r.AdditionalParameters,
+		ClientID:             r.ClientID,
+		PreAuth:              r.PreAuth,
+		CurrencyCode:         r.CurrencyCode,
+		Email:                r.Email,
+		Phone:                r.Phone,
+		FailURL:              r.FailURL,
+		OrderBundle:          r.OrderBundle,
+	}
+}
+
+// YandexPayRequest converts the payment to a Yandex Pay request.
+func (r *WalletPayment) YandexPayRequest() *YandexPayRequest {
+	return &YandexPayRequest{
+		Merchant:             r.Merchant,
+		OrderNumber:          r.OrderNumber,
+		PaymentToken:         r.PaymentToken,
+		Amount:               r.Amount,
+		IP:                   r.IP,
+		ReturnURL:            r.ReturnURL,
+		Description:          r.Description,
+		Language:             r.Language,
+		AdditionalParameters: r.AdditionalParameters,
+		ClientID:             r.ClientID,
+		PreAuth:              r.PreAuth,
+		CurrencyCode:         r.CurrencyCode,
+		Email:                r.Email,
+		Phone:                r.Phone,
+		FailURL:              r.FailURL,
+		OrderBundle:          r.OrderBundle,
+	}
+}
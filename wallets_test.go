@@ -0,0 +1,82 @@
+package alfapay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KlimGrishanov/alfapay"
+)
+
+func newWalletPayment(direct bool) *alfapay.WalletPayment {
+	return &alfapay.WalletPayment{
+		Merchant:     "merchant",
+		OrderNumber:  "ORDER-1",
+		PaymentToken: "token",
+		Amount:       1000,
+		IP:           "127.0.0.1",
+		ReturnURL:    "https://shop.example.com/return",
+		Direct:       direct,
+	}
+}
+
+func TestWalletPayRoutes(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"success":true,"data":{"orderId":"order-1"}}`))
+	}))
+	defer srv.Close()
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL))
+
+	tests := []struct {
+		provider alfapay.WalletProvider
+		direct   bool
+		path     string
+	}{
+		{alfapay.WalletApplePay, false, "/applepay/payment.do"},
+		{alfapay.WalletGooglePay, false, "/google/payment.do"},
+		{alfapay.WalletSamsungPay, false, "/samsung/payment.do"},
+		{alfapay.WalletSamsungPay, true, "/samsung/paymentDirect.do"},
+		{alfapay.WalletMirPay, false, "/mir/payment.do"},
+		{alfapay.WalletMirPay, true, "/mir/paymentDirect.do"},
+		{alfapay.WalletYandexPay, false, "/yandex/payment.do"},
+		{alfapay.WalletYandexPay, true, "/yandex/paymentDirect.do"},
+	}
+	for _, tt := range tests {
+		path = ""
+		result, err := client.Wallets.Pay(context.Background(), tt.provider, newWalletPayment(tt.direct))
+		if err != nil {
+			t.Errorf("%s (direct %v): %v", tt.provider, tt.direct, err)
+			continue
+		}
+		if path != tt.path {
+			t.Errorf("%s (direct %v) sent to %s, want %s", tt.provider, tt.direct, path, tt.path)
+		}
+		if !result.Success || result.OrderID != "order-1" || result.Provider != tt.provider {
+			t.Errorf("%s (direct %v) result = %+v", tt.provider, tt.direct, result)
+		}
+	}
+}
+
+func TestWalletPayDirectUnsupported(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+	client := alfapay.NewClient("any", "any", alfapay.WithBaseURL(srv.URL))
+
+	for _, provider := range []alfapay.WalletProvider{alfapay.WalletApplePay, alfapay.WalletGooglePay} {
+		_, err := client.Wallets.Pay(context.Background(), provider, newWalletPayment(true))
+		if err == nil || !strings.Contains(err.Error(), "direct") {
+			t.Errorf("%s direct payment: err = %v, want unsupported", provider, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent, want none", requests)
+	}
+}